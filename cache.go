@@ -0,0 +1,176 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package gogather
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// ErrInvalidDigest is returned by a DirCache given a digest that is not of the form "<algorithm>:<hex>".
+var ErrInvalidDigest = errors.New("invalid digest")
+
+// digestPattern matches the digests a DirCache accepts, which are safe to use as path elements
+var digestPattern = regexp.MustCompile(`^([a-z0-9]+):([a-f0-9]+)$`)
+
+// Cache holds gathered content by its digest, e.g. "sha256:<hex>", so that content that has already been
+// gathered is copied from the cache rather than downloaded again.
+type Cache interface {
+	// Get copies the content cached under digest to the destination, reporting whether it was cached.
+	Get(digest, destination string) (bool, error)
+	// Put adds the file or directory at path to the cache under digest.
+	Put(digest, path string) error
+}
+
+// DirCache is a Cache holding content in the directory Dir, with the content of each digest at
+// "<Dir>/<algorithm>/<hex>".
+type DirCache struct {
+	Dir string
+}
+
+// Get copies the file or directory cached under digest to the destination, reporting whether it was cached.
+// The files of a cached directory are copied into the destination directory.
+func (c *DirCache) Get(digest, destination string) (bool, error) {
+	path, err := c.path(digest)
+	if err != nil {
+		return false, err
+	}
+
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read cache: %w", err)
+	}
+
+	if !info.IsDir() {
+		err = copyFile(path, destination, info.Mode())
+	} else {
+		err = copyTree(path, destination)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to copy %s from cache: %w", digest, err)
+	}
+	return true, nil
+}
+
+// Put copies the file or directory at path into the cache under digest, replacing anything cached under it.
+// The content is copied to a temporary path in the cache first, so a partially copied entry is never read.
+func (c *DirCache) Put(digest, path string) error {
+	cachePath, err := c.path(digest)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(cachePath), ".tmp-")
+	if err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	entry := filepath.Join(tmp, "entry")
+	if !info.IsDir() {
+		err = copyFile(path, entry, info.Mode())
+	} else {
+		err = copyTree(path, entry)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to copy %s to cache: %w", path, err)
+	}
+
+	if err := os.RemoveAll(cachePath); err != nil {
+		return fmt.Errorf("failed to replace cached %s: %w", digest, err)
+	}
+	if err := os.Rename(entry, cachePath); err != nil {
+		return fmt.Errorf("failed to add %s to cache: %w", digest, err)
+	}
+	return nil
+}
+
+// path returns the path of the content cached under digest
+func (c *DirCache) path(digest string) (string, error) {
+	parts := digestPattern.FindStringSubmatch(digest)
+	if parts == nil {
+		return "", fmt.Errorf("%w: %q", ErrInvalidDigest, digest)
+	}
+	return filepath.Join(c.Dir, parts[1], parts[2]), nil
+}
+
+// copyTree copies the files and directories within src into dst, creating it if needed
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		return copyFile(path, target, info.Mode())
+	})
+}
+
+// copyFile copies the file at src to dst with the given mode, creating the directory of dst if needed
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,75 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package gogather
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestDirCache tests caching files and directories in a DirCache.
+func TestDirCache(t *testing.T) {
+	cache := &DirCache{Dir: t.TempDir()}
+	const fileDigest = "sha256:0123456789abcdef"
+	const dirDigest = "sha256:fedcba9876543210"
+
+	if hit, err := cache.Get(fileDigest, filepath.Join(t.TempDir(), "file.txt")); hit || err != nil {
+		t.Fatalf("Expected a miss, but got %v, %v", hit, err)
+	}
+
+	src := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(src, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "a.txt"), []byte("hello"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "sub", "b.txt"), []byte("world"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cache.Put(fileDigest, filepath.Join(src, "a.txt")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := cache.Put(dirDigest, src); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	file := filepath.Join(t.TempDir(), "file.txt")
+	if hit, err := cache.Get(fileDigest, file); !hit || err != nil {
+		t.Fatalf("Expected a hit, but got %v, %v", hit, err)
+	}
+	if content, err := os.ReadFile(file); err != nil || string(content) != "hello" {
+		t.Errorf("Expected the cached file content, but got %q, %v", content, err)
+	}
+
+	dir := t.TempDir()
+	if hit, err := cache.Get(dirDigest, dir); !hit || err != nil {
+		t.Fatalf("Expected a hit, but got %v, %v", hit, err)
+	}
+	if content, err := os.ReadFile(filepath.Join(dir, "sub", "b.txt")); err != nil || string(content) != "world" {
+		t.Errorf("Expected the cached directory content, but got %q, %v", content, err)
+	}
+
+	for _, digest := range []string{"", "sha256", "sha256:../../etc", "../sha256:abc"} {
+		if err := cache.Put(digest, src); !errors.Is(err, ErrInvalidDigest) {
+			t.Errorf("Expected ErrInvalidDigest for %q, but got %v", digest, err)
+		}
+	}
+}
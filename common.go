@@ -17,12 +17,18 @@
 package gogather
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 )
 
 // URLType is an enum for URL types
@@ -34,13 +40,48 @@ const (
 	FileURI
 	OCIURI
 	Unknown
+	DataURI
+	GitHubURI
 )
 
+// DefaultDirMode is the mode gatherers create destination directories with, unless they are configured otherwise.
+const DefaultDirMode os.FileMode = 0755
+
 var getHomeDir = os.UserHomeDir
 
+// NowFunc returns the current time, which gatherers stamp metadata with and the tar expander gives to entries that
+// have none. Tests can replace it to make timestamps deterministic.
+var NowFunc = time.Now
+
+// GitHosts are the hosts of web git services, whose sources are classified as git repositories without a
+// "git::" prefix or scheme, e.g. "github.com/org/repo". Self-hosted instances, e.g. of GitLab or Gitea, can be added.
+var GitHosts = []string{"github.com", "gitlab.com", "bitbucket.org"}
+
+// gitHostAssetPattern matches the paths of files that git hosts serve over HTTP, rather than repositories,
+// e.g. "org/repo/releases/download/v1/app.tar.gz" or "org/repo/-/raw/main/policy.rego".
+var gitHostAssetPattern = regexp.MustCompile(`^[^/]+/[^/]+/(-/)?(releases/download|raw|archive)/`)
+
+// GathererInfo describes the sources a gatherer handles and how it writes them to the destination
+type GathererInfo struct {
+	// Name is the name of the gatherer, e.g. "git"
+	Name string
+	// Prefixes are the source prefixes handled by the gatherer, e.g. "git::"
+	Prefixes []string
+	// Extracts is true if the gatherer expands archives into the destination
+	Extracts bool
+	// Destination describes how the gatherer interprets the destination
+	Destination string
+}
+
+// ErrNetworkDisabled is returned by gatherers that need network access when they are configured to work offline.
+var ErrNetworkDisabled = errors.New("network access is disabled")
+
+// ErrInvalidDestination is returned when a destination cannot be written, because it is within a file or a directory that is not writable.
+var ErrInvalidDestination = errors.New("invalid destination")
+
 // String returns the string representation of the URLType
 func (t URIType) String() string {
-	return [...]string{"GitURI", "HTTPURI", "FileURI", "OCIURI", "Unknown"}[t]
+	return [...]string{"GitURI", "HTTPURI", "FileURI", "OCIURI", "Unknown", "DataURI", "GitHubURI"}[t]
 }
 
 // ExpandTilde expands a leading tilde in the file path to the user's home directory
@@ -72,10 +113,24 @@ func ClassifyURI(input string) (URIType, error) {
 		return OCIURI, nil
 	}
 
-	if strings.HasPrefix(input, "github.com") || strings.HasPrefix(input, "gitlab.com") {
+	if strings.HasPrefix(input, "data:") {
+		return DataURI, nil
+	}
+
+	if strings.HasPrefix(input, "github://") {
+		return GitHubURI, nil
+	}
+
+	if strings.HasPrefix(input, "ssh://") {
 		return GitURI, nil
 	}
 
+	for _, host := range GitHosts {
+		if input == host || (strings.HasPrefix(input, host+"/") && !gitHostAssetPattern.MatchString(input[len(host)+1:])) {
+			return GitURI, nil
+		}
+	}
+
 	// Regular expression for Git URIs
 	gitURIPattern := regexp.MustCompile(`^(git@[\w\.\-]+:[\w\.\-]+/[\w\.\-]+(\.git)?|https?://[\w\.\-]+/[\w\.\-]+/[\w\.\-]+(\.git)?|git://[\w\.\-]+/[\w\.\-]+/[\w\.\-]+(\.git)?|[\w\.\-]+/[\w\.\-]+/[\w\.\-]+//.*|file://.*\.git|[\w\.\-]+/[\w\.\-]+(\.git)?)$`)
 	// Regular expression for HTTP URIs (with or without protocol)
@@ -136,6 +191,76 @@ func ClassifyURI(input string) (URIType, error) {
 	return Unknown, nil
 }
 
+// GetDirectorySize returns the total size in bytes, and the number, of the regular files within the directory at path, recursively.
+func GetDirectorySize(path string) (size int64, files int, err error) {
+	err = filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+			files++
+		}
+		return nil
+	})
+	return size, files, err
+}
+
+// GetDirectorySizeFS is GetDirectorySize for the files of fsys, such as those gathered into a saver.MemoryFS.
+func GetDirectorySizeFS(fsys fs.FS) (size int64, files int, err error) {
+	err = fs.WalkDir(fsys, ".", func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		files++
+		return nil
+	})
+	return size, files, err
+}
+
+// DirectoryDigest returns the hex encoded sha256 digest of the regular files within the directory at path,
+// recursively. It hashes the path of each file relative to the directory and its content, in lexical order of
+// the paths, so the digest of a copy of the directory is the same wherever it is.
+func DirectoryDigest(path string) (string, error) {
+	return DirectoryDigestFS(os.DirFS(path))
+}
+
+// DirectoryDigestFS is DirectoryDigest for the files of fsys, such as those gathered into a saver.MemoryFS.
+func DirectoryDigestFS(fsys fs.FS) (string, error) {
+	h := sha256.New()
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		// The path and size delimit the content of each file, so no two trees hash alike
+		fmt.Fprintf(h, "%s\x00%d\x00", p, info.Size())
+
+		f, err := fsys.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(h, f)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
 // ValidateFileDestination validates the destination path for saving files
 func ValidateFileDestination(destination string) error {
 	// Expand the tilde in the file path if it exists
@@ -151,22 +276,61 @@ func ValidateFileDestination(destination string) error {
 	return nil
 }
 
-// containsOCIRegistry checks if the input string contains a known OCI registry
-func containsOCIRegistry(src string) bool {
-	matchRegistries := []*regexp.Regexp{
-		regexp.MustCompile("azurecr.io"),
-		regexp.MustCompile("gcr.io"),
-		regexp.MustCompile("registry.gitlab.com"),
-		regexp.MustCompile("pkg.dev"),
-		regexp.MustCompile("[0-9]{12}.dkr.ecr.[a-z0-9-]*.amazonaws.com"),
-		regexp.MustCompile("^quay.io"),
-		regexp.MustCompile(`(?:::1|127\.0\.0\.1|(?i:localhost)):\d{1,5}`), // localhost OCI registry
+// ValidateDestination checks that the destination can be written before anything is gathered: the nearest
+// existing parent of the destination must be a directory that is writable. A "file::" or "file://" prefix is ignored.
+func ValidateDestination(destination string) error {
+	path := ExpandTilde(strings.TrimPrefix(strings.TrimPrefix(destination, "file::"), "file://"))
+	if path == "" {
+		return nil
 	}
 
-	for _, matchRegistry := range matchRegistries {
+	dir := filepath.Dir(filepath.Clean(path))
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("%w: %s is within %s, which is not a directory", ErrInvalidDestination, destination, dir)
+			}
+			break
+		}
+		// The parent may not exist, or may be within a file, so keep looking for the nearest one that does
+		if filepath.Dir(dir) == dir {
+			return nil
+		}
+		dir = filepath.Dir(dir)
+	}
+
+	// Creating a file is the only reliable way to tell if a directory is writable, e.g. on a read-only mount
+	f, err := os.CreateTemp(dir, ".go-gather-*")
+	if err != nil {
+		return fmt.Errorf("%w: %s is not writable: %w", ErrInvalidDestination, dir, err)
+	}
+	_ = f.Close()
+	return os.Remove(f.Name())
+}
+
+// ociRegistryPatterns match the host of known OCI registries, and of registries running on the loopback interface
+var ociRegistryPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^([\w\-]+\.)*azurecr\.io([:/]|$)`),
+	regexp.MustCompile(`^([\w\-]+\.)*gcr\.io([:/]|$)`),
+	regexp.MustCompile(`^registry\.gitlab\.com([:/]|$)`),
+	regexp.MustCompile(`^([\w\-]+\.)*pkg\.dev([:/]|$)`),
+	regexp.MustCompile(`^[0-9]{12}\.dkr\.ecr\.[a-z0-9-]*\.amazonaws\.com([:/]|$)`),
+	regexp.MustCompile(`^quay\.io([:/]|$)`),
+	regexp.MustCompile(`^(::1|\[::1\]|127\.0\.0\.1|(?i:localhost)):\d{1,5}(/|$)`), // localhost OCI registry
+}
+
+// ociReferencePattern matches a reference to an image on any registry with an explicit port. As a schemeless
+// host:port/path is ambiguous, the reference must include a tag or a digest, e.g. example.com:5000/repo:v1.
+var ociReferencePattern = regexp.MustCompile(`^[\w\.\-]+:\d{1,5}/[\w\.\-/]+(:\w[\w\.\-]{0,127}|@sha256:[a-f0-9]{64})$`)
+
+// containsOCIRegistry checks if the input string is a reference to a known OCI registry,
+// a registry on the loopback interface, or a tagged or pinned image on a registry with an explicit port
+func containsOCIRegistry(src string) bool {
+	for _, matchRegistry := range ociRegistryPatterns {
 		if matchRegistry.MatchString(src) {
 			return true
 		}
 	}
-	return false
+	return ociReferencePattern.MatchString(src)
 }
@@ -21,7 +21,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
 )
 
 // TestURITypeString tests the String method of the URIType type.
@@ -33,7 +35,10 @@ func TestURITypeString(t *testing.T) {
 		{input: GitURI, expected: "GitURI"},
 		{input: HTTPURI, expected: "HTTPURI"},
 		{input: FileURI, expected: "FileURI"},
+		{input: OCIURI, expected: "OCIURI"},
 		{input: Unknown, expected: "Unknown"},
+		{input: DataURI, expected: "DataURI"},
+		{input: GitHubURI, expected: "GitHubURI"},
 	}
 
 	for _, tc := range testCases {
@@ -42,6 +47,11 @@ func TestURITypeString(t *testing.T) {
 			t.Errorf("Expected %s.String() to return %s, but got %s", tc.input, tc.expected, actual)
 		}
 	}
+
+	// The values of the URI types are exported, so types added later must not change those of earlier ones
+	if Unknown != 4 {
+		t.Errorf("Expected Unknown to be 4, but got %d", Unknown)
+	}
 }
 
 // TestExpandTilde tests the ExpandTilde function.
@@ -89,6 +99,8 @@ func TestClassifyURI(t *testing.T) {
 	}{
 		{input: "git::git@github.com:user/repo.git", expected: GitURI},
 		{input: "git@github.com:user/repo.git", expected: GitURI},
+		{input: "ssh://git@example.com/org/repo", expected: GitURI},
+		{input: "ssh://git@example.com:2222/org/repo.git", expected: GitURI},
 		{input: "http::https://github.com/user/repo.git", expected: HTTPURI},
 		{input: "file::/home/user/file.txt", expected: FileURI},
 		{input: "file:///home/user/file.txt", expected: FileURI},
@@ -97,6 +109,7 @@ func TestClassifyURI(t *testing.T) {
 		{input: "ftpexamplecom", expected: Unknown},
 		{input: "github.com/user/repo.git", expected: GitURI},
 		{input: "gitlab.com/user/repo.git", expected: GitURI},
+		{input: "bitbucket.org/user/repo.git", expected: GitURI},
 		{input: "oci::registry.gitlab.com/user/repo:latest", expected: OCIURI},
 		{input: "oci::registry.gitlab.com/user/repo", expected: OCIURI},
 		{input: "oci::registry.gitlab.com/user/repo:1.0.0", expected: OCIURI},
@@ -108,6 +121,9 @@ func TestClassifyURI(t *testing.T) {
 		{input: "123456789012.dkr.ecr.us-west-2.amazonaws.com/user/repo:latest", expected: OCIURI},
 		{input: "gcr.io/user/repo:latest", expected: OCIURI},
 		{input: "azurecr.io/user/repo:latest", expected: OCIURI},
+		{input: "data:text/plain;base64,aGVsbG8=", expected: DataURI},
+		{input: "data:,hello", expected: DataURI},
+		{input: "github://org/repo/v1.0.0/policy.tar.gz", expected: GitHubURI},
 	}
 
 	for _, tc := range testCases {
@@ -213,6 +229,12 @@ func TestContainsOCIRegistry(t *testing.T) {
 		{input: "127.0.0.1:8080", expected: true},
 		{input: "localhost:8080", expected: true},
 		{input: "example.com", expected: false},
+		{input: "myregistry.azurecr.io/repo:latest", expected: true},
+		{input: "us-docker.pkg.dev/project/repo:latest", expected: true},
+		{input: "[::1]:5000/repo", expected: true},
+		{input: "localhost:5000/repo", expected: true},
+		{input: "example.com/gcr.io", expected: false},
+		{input: "notquay.io/repo:latest", expected: false},
 	}
 
 	for _, tc := range testCases {
@@ -222,3 +244,224 @@ func TestContainsOCIRegistry(t *testing.T) {
 		}
 	}
 }
+
+// TestClassifyURI_hostPort tests the classification of inputs containing a host and port.
+func TestClassifyURI_hostPort(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+
+	testCases := []struct {
+		input       string
+		expected    URIType
+		errExpected bool
+	}{
+		{input: "http://example.com:8080/file.txt", expected: HTTPURI},
+		{input: "https://example.com:8443/repo:v1", expected: HTTPURI},
+		{input: "localhost:32433/repository:tag", expected: OCIURI},
+		{input: "127.0.0.1:5000/repository", expected: OCIURI},
+		{input: "example.com:5000/repo:v1", expected: OCIURI},
+		{input: "example.com:5000/org/repo:1.0.0", expected: OCIURI},
+		{input: "example.com:5000/repo@" + digest, expected: OCIURI},
+		{input: "example.com:5000/repo", expected: Unknown, errExpected: true},
+		{input: "example.com:5000/file.txt", expected: Unknown, errExpected: true},
+	}
+
+	for _, tc := range testCases {
+		actual, err := ClassifyURI(tc.input)
+		if tc.errExpected && err == nil {
+			t.Errorf("Expected ClassifyURI(%s) to return an error, but got nil", tc.input)
+		}
+		if !tc.errExpected && err != nil {
+			t.Errorf("Unexpected error for %s: %v", tc.input, err)
+		}
+		if actual != tc.expected {
+			t.Errorf("Expected ClassifyURI(%s) to return %s, but got %s", tc.input, tc.expected, actual)
+		}
+	}
+}
+
+// TestGetDirectorySize tests the GetDirectorySize function.
+func TestGetDirectorySize(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("hello"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sub", "b.txt"), []byte("world!"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	size, files, err := GetDirectorySize(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if size != 11 || files != 2 {
+		t.Errorf("Expected GetDirectorySize to return 11 bytes in 2 files, but got %d bytes in %d files", size, files)
+	}
+
+	if _, _, err := GetDirectorySize(filepath.Join(dir, "missing")); err == nil {
+		t.Error("Expected an error, but got nil")
+	}
+}
+
+// TestDirectoryDigest tests the DirectoryDigest function.
+func TestDirectoryDigest(t *testing.T) {
+	populate := func(t *testing.T, files map[string]string) string {
+		dir := t.TempDir()
+		for name, content := range files {
+			path := filepath.Join(dir, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return dir
+	}
+	digest := func(t *testing.T, dir string) string {
+		d, err := DirectoryDigest(dir)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return d
+	}
+
+	files := map[string]string{"a.txt": "hello", "sub/b.txt": "world!"}
+	original := digest(t, populate(t, files))
+	if len(original) != 64 {
+		t.Errorf("Expected a hex encoded sha256 digest, but got %q", original)
+	}
+	if copied := digest(t, populate(t, files)); copied != original {
+		t.Errorf("Expected a copy to have digest %s, but got %s", original, copied)
+	}
+
+	changes := []map[string]string{
+		{"a.txt": "hellO", "sub/b.txt": "world!"},
+		{"a.txt": "hello", "sub/c.txt": "world!"},
+		{"a.txt": "hello", "sub/b.txt": "world!", "c.txt": ""},
+		{"a.txt": "hellow", "sub/b.txt": "orld!"},
+	}
+	for _, changed := range changes {
+		if d := digest(t, populate(t, changed)); d == original {
+			t.Errorf("Expected %v to have a digest other than %s", changed, original)
+		}
+	}
+
+	if _, err := DirectoryDigest(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("Expected an error, but got nil")
+	}
+
+	// The files of an fs.FS have the same digest and size as the same files on disk
+	fsys := fstest.MapFS{}
+	for name, content := range files {
+		fsys[name] = &fstest.MapFile{Data: []byte(content)}
+	}
+	if d, err := DirectoryDigestFS(fsys); err != nil || d != original {
+		t.Errorf("Expected the fs.FS to have digest %s, but got %s (%v)", original, d, err)
+	}
+	if size, count, err := GetDirectorySizeFS(fsys); err != nil || size != 11 || count != 2 {
+		t.Errorf("Expected the fs.FS to hold 2 files of 11 bytes, but got %d files of %d bytes (%v)", count, size, err)
+	}
+}
+
+// TestValidateDestination tests the ValidateDestination function.
+func TestValidateDestination(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file.txt")
+	if err := os.WriteFile(file, []byte("content"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name        string
+		destination string
+		valid       bool
+	}{
+		{name: "existing directory", destination: dir, valid: true},
+		{name: "new directory", destination: filepath.Join(dir, "a", "b"), valid: true},
+		{name: "existing file", destination: file, valid: true},
+		{name: "file URI", destination: "file://" + filepath.Join(dir, "out"), valid: true},
+		{name: "within a file", destination: filepath.Join(file, "out"), valid: false},
+		{name: "nested within a file", destination: "file://" + filepath.Join(file, "a", "b"), valid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateDestination(tc.destination)
+			if tc.valid && err != nil {
+				t.Errorf("Expected no error, but got %v", err)
+			}
+			if !tc.valid {
+				if !errors.Is(err, ErrInvalidDestination) {
+					t.Fatalf("Expected ErrInvalidDestination, but got %v", err)
+				}
+				if !strings.Contains(err.Error(), "within "+file+",") {
+					t.Errorf("Expected the error to name %s, but got %v", file, err)
+				}
+			}
+		})
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected nothing to be left in %s, but got %v", dir, entries)
+	}
+}
+
+// TestClassifyURI_GitHosts tests classifying the sources of a git host added to GitHosts.
+func TestClassifyURI_GitHosts(t *testing.T) {
+	if _, err := ClassifyURI("gitea.internal/org/repo"); err == nil {
+		t.Fatal("Expected an error for an unknown host without a scheme, but got nil")
+	}
+
+	original := GitHosts
+	GitHosts = append([]string{"gitea.internal"}, GitHosts...)
+	t.Cleanup(func() { GitHosts = original })
+
+	for _, input := range []string{"gitea.internal/org/repo", "gitea.internal/org/repo//policy?ref=main", "https://gitea.internal/org/repo"} {
+		actual, err := ClassifyURI(input)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if actual != GitURI {
+			t.Errorf("Expected ClassifyURI(%s) to return %s, but got %s", input, GitURI, actual)
+		}
+	}
+}
+
+// TestClassifyURI_GitHostAssets tests that files served by a git host are classified as HTTP sources, and its repositories as git.
+func TestClassifyURI_GitHostAssets(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected URIType
+	}{
+		{input: "https://github.com/org/repo/releases/download/v1/app.tar.gz", expected: HTTPURI},
+		{input: "https://github.com/org/repo/raw/main/policy.rego", expected: HTTPURI},
+		{input: "https://github.com/org/repo/archive/refs/tags/v1.tar.gz", expected: HTTPURI},
+		{input: "https://gitlab.com/org/repo/-/archive/v1/repo-v1.tar.gz", expected: HTTPURI},
+		{input: "https://github.com/org/repo", expected: GitURI},
+		{input: "https://github.com/org/repo.git", expected: GitURI},
+		{input: "github.com/org/repo", expected: GitURI},
+		{input: "github.com/org/releases", expected: GitURI},
+	}
+
+	for _, tc := range testCases {
+		actual, err := ClassifyURI(tc.input)
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v", tc.input, err)
+		}
+		if actual != tc.expected {
+			t.Errorf("Expected ClassifyURI(%s) to return %s, but got %s", tc.input, tc.expected, actual)
+		}
+	}
+
+	// Without a scheme, an asset is not mistaken for a repository, and needs one to be downloaded
+	if actual, err := ClassifyURI("github.com/org/repo/releases/download/v1/app.tar.gz"); err == nil || actual == GitURI {
+		t.Errorf("Expected an error asking for a scheme, but got %s, %v", actual, err)
+	}
+}
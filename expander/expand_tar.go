@@ -18,78 +18,174 @@ package expander
 
 import (
 	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
+
+	gogather "github.com/enterprise-contract/go-gather"
 )
 
-// untar is a helper function that untars a tarball to a destination directory
-func untar(input io.Reader, dst, src string, dir bool, umask os.FileMode, fileSizeLimit int64, filesLimit int) error {
+// blockSize is the size of a tar header block
+const blockSize = 512
+
+// gzipMagic is the magic number at the start of gzip compressed data
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// isGzipTar reports whether the file at path is named as a gzip compressed tar archive
+func isGzipTar(path string) bool {
+	return hasExtension(path, ".tar.gz") || hasExtension(path, ".tgz")
+}
+
+// isTarStream reports whether the stream read by br starts with a tar header, without consuming it.
+// A stream starting with a zero block is also accepted, as that is how an empty tar archive is written.
+func isTarStream(br *bufio.Reader) bool {
+	block, err := br.Peek(blockSize)
+	if err != nil {
+		return false
+	}
+	if bytes.Equal(block, make([]byte, blockSize)) {
+		return true
+	}
+	// Both the POSIX ("ustar\x00") and GNU ("ustar ") formats start the magic field with "ustar"
+	return bytes.HasPrefix(block[257:], []byte("ustar"))
+}
+
+// tarDir records a directory created during extraction so its mode and times can be applied afterwards
+type tarDir struct {
+	path   string
+	header *tar.Header
+}
+
+// expansion tracks what has been extracted from an archive and the archives nested within it, so that the limits
+// apply to the expansion as a whole rather than to each archive in it.
+type expansion struct {
+	fileSizeLimit int64
+	filesLimit    int
+	fileSize      int64
+	files         int
+	dirs          int
+}
+
+// newExpansion returns the expansion of an archive with the limits of the expander.
+func (t *TarExpander) newExpansion() (*expansion, error) {
+	fileSizeLimit, filesLimit, err := t.limits()
+	if err != nil {
+		return nil, err
+	}
+	return &expansion{fileSizeLimit: fileSizeLimit, filesLimit: filesLimit}, nil
+}
+
+// untar is a helper function that untars a tarball to a destination directory, returning what was extracted.
+// If RecursiveExpand is set, it also returns the paths of the extracted files that are themselves archives.
+// What is extracted counts towards the limits of the expansion e.
+func (t *TarExpander) untar(ctx context.Context, input io.Reader, dst, src string, dir bool, umask os.FileMode, e *expansion) (ExpandResult, []string, error) {
 	tarReader := tar.NewReader(input)
 	finished := false
 
-	dirHeaders := []*tar.Header{}
-	now := time.Now()
+	dirs := []tarDir{}
+	extracted := gogather.NowFunc()
+
+	sanitize := t.NameSanitizer
+	if sanitize == nil {
+		sanitize = DefaultNameSanitizer
+	}
 
 	var (
-		fileSize   int64
-		filesCount int
+		result        ExpandResult
+		archives      []string
+		fileSizeLimit = e.fileSizeLimit
+		filesLimit    = e.filesLimit
 	)
 
 	for {
-		if filesLimit > 0 {
-			filesCount++
-			if filesCount > filesLimit {
-				return fmt.Errorf("tar file contains more files than the %d allowed: %d", filesCount, filesLimit)
-			}
+		if err := ctx.Err(); err != nil {
+			return result, nil, err
 		}
 
 		header, err := tarReader.Next()
 		if err == io.EOF {
 			if !finished {
 				// Empty archive
-				return fmt.Errorf("tar file is empty: %s", src)
+				return result, nil, fmt.Errorf("%w: %s", ErrEmptyArchive, src)
 			}
 			break
 		}
 
 		if err != nil {
-			return err
+			return result, nil, err
 		}
 
+		// The tar reader applies PAX records, such as names longer than 100 characters, to the entries that follow them
 		if header.Typeflag == tar.TypeXGlobalHeader || header.Typeflag == tar.TypeXHeader {
 			continue
 		}
 
+		if filesLimit > 0 {
+			e.files++
+			if e.files > filesLimit {
+				return result, nil, fmt.Errorf("%w: tar file contains more files than the %d allowed: %d", ErrFilesLimitExceeded, filesLimit, e.files)
+			}
+		}
+
 		fPath := dst
 
 		if dir {
-			if containsDotDot(header.Name) {
-				return fmt.Errorf("tar file (%s) would escape destination directory", header.Name)
+			name, err := sanitize(header.Name)
+			if err != nil {
+				return result, nil, err
+			}
+
+			if t.StripComponents > 0 {
+				parts := strings.FieldsFunc(name, isSlash)
+				if len(parts) <= t.StripComponents {
+					// Skip entries that have no path left once stripped, like tar --strip-components
+					continue
+				}
+				name = strings.Join(parts[t.StripComponents:], "/")
+			}
+
+			if containsDotDot(name) || hasDriveLetter(name) {
+				return result, nil, fmt.Errorf("%w: %s", ErrPathTraversal, name)
 			}
 
-			fPath = filepath.Join(dst, header.Name) // nolint:gosec
+			if selected, err := selectEntry(name, t.Include, t.Exclude); err != nil {
+				return result, nil, err
+			} else if !selected {
+				continue
+			}
+
+			fPath = filepath.Join(dst, name) // nolint:gosec
 		}
 
 		fileInfo := header.FileInfo()
-		fileSize += fileInfo.Size()
+		e.fileSize += fileInfo.Size()
 
-		if fileSizeLimit > 0 && fileSize > fileSizeLimit {
-			return fmt.Errorf("tar file size exceeds the %d limit: %d", fileSizeLimit, fileSize)
+		if fileSizeLimit > 0 && e.fileSize > fileSizeLimit {
+			return result, nil, fmt.Errorf("%w: tar file size exceeds the %d limit: %d", ErrSizeLimitExceeded, fileSizeLimit, e.fileSize)
 		}
 
 		if fileInfo.IsDir() {
 			if !dir {
-				return fmt.Errorf("expected a file (%s), got a directory: %s", src, fPath)
+				return result, nil, fmt.Errorf("expected a file (%s), got a directory: %s", src, fPath)
+			}
+
+			if t.DirsLimit > 0 && e.dirs >= t.DirsLimit {
+				return result, nil, fmt.Errorf("%w: tar file contains more directories than the %d allowed", ErrDirsLimitExceeded, t.DirsLimit)
 			}
+			e.dirs++
 
 			if err := os.MkdirAll(fPath, umask); err != nil {
-				return fmt.Errorf("failed to create directory (%s): %s", fPath, err)
+				return result, nil, fmt.Errorf("failed to create directory (%s): %s", fPath, err)
 			}
 
-			dirHeaders = append(dirHeaders, header)
+			dirs = append(dirs, tarDir{path: fPath, header: header})
 
 			continue
 		} else {
@@ -97,71 +193,170 @@ func untar(input io.Reader, dst, src string, dir bool, umask os.FileMode, fileSi
 
 			if _, err := os.Stat(destPath); os.IsNotExist(err) {
 				if err := os.MkdirAll(destPath, umask); err != nil {
-					return fmt.Errorf("failed to create directory (%s): %s", destPath, err)
+					return result, nil, fmt.Errorf("failed to create directory (%s): %s", destPath, err)
 				}
 			}
 		}
 
 		if !dir && finished {
-			return fmt.Errorf("tar file contains more than one file: %s", src)
+			return result, nil, fmt.Errorf("tar file contains more than one file: %s", src)
 		}
 
 		finished = true
 
-		err = copyReader(tarReader, fPath, umask, fileSizeLimit)
+		err = copyReader(ctx, tarReader, fPath, umask, fileSizeLimit)
 		if err != nil {
-			return err
+			return result, nil, err
 		}
 
-		aTime, mTime := now, now
+		aTime, mTime := extracted, extracted
 
 		if header.AccessTime.Unix() > 0 {
 			aTime = header.AccessTime
 		}
 
 		if header.ModTime.Unix() > 0 {
-			mTime = header.ModTime
+			mTime = t.clampModTime(header.ModTime, extracted)
 		}
 
 		if err := os.Chtimes(fPath, aTime, mTime); err != nil {
-			return fmt.Errorf("failed to change file times (%s): %s", fPath, err)
+			return result, nil, fmt.Errorf("failed to change file times (%s): %s", fPath, err)
 		}
-	}
 
-	for _, dirHeader := range dirHeaders {
-		if containsDotDot(dirHeader.Name) {
-			return fmt.Errorf("tar file (%s) would escape destination directory", dirHeader.Name)
+		result.Files++
+		result.Size += fileInfo.Size()
+
+		if t.RecursiveExpand && IsArchive(fPath) {
+			archives = append(archives, fPath)
 		}
-		path := filepath.Join(dst, dirHeader.Name) // nolint:gosec
+	}
+
+	for _, d := range dirs {
+		path, dirHeader := d.path, d.header
 		// Chmod the directory
 		if err := os.Chmod(path, dirHeader.FileInfo().Mode()); err != nil {
-			return fmt.Errorf("failed to change directory permissions (%s): %s", path, err)
+			return result, nil, fmt.Errorf("failed to change directory permissions (%s): %s", path, err)
 		}
 
 		// Set the access and modification times
-		aTime, mTime := now, now
+		aTime, mTime := extracted, extracted
 
 		if dirHeader.AccessTime.Unix() > 0 {
 			aTime = dirHeader.AccessTime
 		}
 		if dirHeader.ModTime.Unix() > 0 {
-			mTime = dirHeader.ModTime
+			mTime = t.clampModTime(dirHeader.ModTime, extracted)
 		}
 		if err := os.Chtimes(path, aTime, mTime); err != nil {
-			return fmt.Errorf("failed to change directory times (%s): %s", path, err)
+			return result, nil, fmt.Errorf("failed to change directory times (%s): %s", path, err)
 		}
 	}
-	return nil
+	return result, archives, nil
+}
+
+// ExpandResult describes the files extracted from an archive.
+type ExpandResult struct {
+	// Files is the number of regular files extracted.
+	Files int
+	// Size is the total size in bytes of the regular files extracted, as recorded in the archive.
+	Size int64
 }
 
 type TarExpander struct {
+	// FileSizeLimit is the maximum total size in bytes of the extracted files, and FilesLimit the maximum
+	// number of entries. If zero, the limit set by MaxFileSizeEnv or MaxFilesEnv is used, if any.
 	FileSizeLimit int64
 	FilesLimit    int
+	// DirsLimit is the maximum number of directory entries an archive may contain.
+	// The mode and times of each directory are applied once all files have been extracted,
+	// so this also bounds the memory used to track them. Zero means no limit.
+	DirsLimit int
+	// NameSanitizer rewrites or rejects the name of each entry before it is extracted.
+	// If nil, DefaultNameSanitizer is used.
+	NameSanitizer func(string) (string, error)
+	// Include is a list of glob patterns; if set, only entries matching one of them are extracted.
+	// See selectEntry for the pattern syntax.
+	Include []string
+	// Exclude is a list of glob patterns; entries matching any of them are not extracted.
+	Exclude []string
+	// StripComponents is the number of leading path components removed from the name of each entry,
+	// like tar --strip-components. Entries with no more than this many components are skipped.
+	StripComponents int
+	// RecursiveExpand expands any archives found among the extracted files in place,
+	// replacing each with a directory of the same name without its extension.
+	RecursiveExpand bool
+	// MaxDepth is the maximum number of levels of archives expanded when RecursiveExpand is set,
+	// counting the outermost archive. If zero, DefaultMaxDepth is used.
+	MaxDepth int
+	// RejectSymlinkDestination makes expansion fail with ErrSymlinkDestination if the destination directory is
+	// a symbolic link, whose target the archive would otherwise be expanded into.
+	RejectSymlinkDestination bool
+	// ClampModTime sets the modification time of any entry dated after MaxModTime to MaxModTime, so that
+	// future-dated entries do not confuse tools relying on timestamps. If MaxModTime is zero, the time of
+	// the extraction is used.
+	ClampModTime bool
+	MaxModTime   time.Time
 }
 
-func (t *TarExpander) Expand(dst, src string, dir bool, umask os.FileMode) error {
-	if !dir {
-		err := os.MkdirAll(dst, umask)
+// clampModTime returns the modification time of an entry extracted at the given time, clamped if ClampModTime is set
+func (t *TarExpander) clampModTime(mTime, extracted time.Time) time.Time {
+	if !t.ClampModTime {
+		return mTime
+	}
+	limit := t.MaxModTime
+	if limit.IsZero() {
+		limit = extracted
+	}
+	if mTime.After(limit) {
+		return limit
+	}
+	return mTime
+}
+
+// checkDestination returns ErrSymlinkDestination if dst is a symbolic link and RejectSymlinkDestination is set.
+func (t *TarExpander) checkDestination(dst string) error {
+	if !t.RejectSymlinkDestination {
+		return nil
+	}
+	if info, err := os.Lstat(dst); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		return fmt.Errorf("%w: %s", ErrSymlinkDestination, dst)
+	}
+	return nil
+}
+
+// limits returns the size and files limits of the expander, falling back to those set in the environment.
+func (t *TarExpander) limits() (int64, int, error) {
+	fileSizeLimit, filesLimit, err := defaultLimits()
+	if err != nil {
+		return 0, 0, err
+	}
+	if t.FileSizeLimit > 0 {
+		fileSizeLimit = t.FileSizeLimit
+	}
+	if t.FilesLimit > 0 {
+		filesLimit = t.FilesLimit
+	}
+	return fileSizeLimit, filesLimit, nil
+}
+
+func (t *TarExpander) Expand(ctx context.Context, dst, src string, dir bool, umask os.FileMode) error {
+	_, err := t.ExpandWithResult(ctx, dst, src, dir, umask)
+	return err
+}
+
+// ExpandWithResult is like Expand, but also returns the number and total size of the files extracted.
+func (t *TarExpander) ExpandWithResult(ctx context.Context, dst, src string, dir bool, umask os.FileMode) (ExpandResult, error) {
+	e, err := t.newExpansion()
+	if err != nil {
+		return ExpandResult{}, err
+	}
+	return t.expand(ctx, dst, src, dir, umask, 0, e)
+}
+
+// ExpandReader expands the tar archive read from r into the dst directory.
+// The archive may be gzip compressed, which is detected from its content.
+func (t *TarExpander) ExpandReader(ctx context.Context, r io.Reader, dst string, umask os.FileMode) error {
+	if err := t.checkDestination(dst); err != nil {
 		return err
 	}
 
@@ -169,10 +364,106 @@ func (t *TarExpander) Expand(dst, src string, dir bool, umask os.FileMode) error
 		return err
 	}
 
-	f, err := os.Open(src)
+	br := bufio.NewReaderSize(r, blockSize)
+	if magic, err := br.Peek(len(gzipMagic)); err == nil && bytes.Equal(magic, gzipMagic) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return fmt.Errorf("failed to read gzip stream: %w", err)
+		}
+		defer gz.Close()
+
+		br = bufio.NewReaderSize(gz, blockSize)
+		if !isTarStream(br) {
+			return fmt.Errorf("%w: stream", ErrNotTarArchive)
+		}
+	}
+
+	e, err := t.newExpansion()
 	if err != nil {
 		return err
 	}
+	_, err = t.expandStream(ctx, br, dst, "stream", umask, 0, e)
+	return err
+}
+
+// expand expands the archive at src, which is nested depth levels deep within the archive originally being expanded
+// as the expansion e
+func (t *TarExpander) expand(ctx context.Context, dst, src string, dir bool, umask os.FileMode, depth int, e *expansion) (ExpandResult, error) {
+	if !dir {
+		err := os.MkdirAll(dst, umask)
+		return ExpandResult{}, err
+	}
+
+	if err := t.checkDestination(dst); err != nil {
+		return ExpandResult{}, err
+	}
+
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return ExpandResult{}, err
+	}
+
+	f, err := os.Open(src)
+	if err != nil {
+		return ExpandResult{}, err
+	}
 	defer f.Close()
-	return untar(f, dst, src, dir, umask, t.FileSizeLimit, t.FilesLimit)
+
+	var input io.Reader = f
+	if isGzipTar(src) {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return ExpandResult{}, fmt.Errorf("failed to read gzip file %s: %w", src, err)
+		}
+		defer gz.Close()
+
+		br := bufio.NewReaderSize(gz, blockSize)
+		if !isTarStream(br) {
+			return ExpandResult{}, fmt.Errorf("%w: %s", ErrNotTarArchive, src)
+		}
+		input = br
+	}
+
+	return t.expandStream(ctx, input, dst, src, umask, depth, e)
+}
+
+// expandStream expands the tar archive read from input into the dst directory, followed by any nested archives
+// if RecursiveExpand is set, all counting towards the limits of the expansion e. The src is the name of the
+// archive used in errors.
+func (t *TarExpander) expandStream(ctx context.Context, input io.Reader, dst, src string, umask os.FileMode, depth int, e *expansion) (ExpandResult, error) {
+	result, archives, err := t.untar(ctx, input, dst, src, true, umask, e)
+	if err != nil {
+		return result, err
+	}
+
+	if len(archives) == 0 {
+		return result, nil
+	}
+
+	maxDepth := t.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxDepth
+	}
+	if depth+1 >= maxDepth {
+		return result, fmt.Errorf("%w: %s contains archives nested more than %d levels deep", ErrMaxDepthExceeded, src, maxDepth)
+	}
+
+	for _, archive := range archives {
+		info, err := os.Stat(archive)
+		if err != nil {
+			return result, err
+		}
+		nested, err := t.expand(ctx, archiveDestination(archive), archive, true, umask, depth+1, e)
+		if err != nil {
+			return result, fmt.Errorf("failed to expand nested archive %s: %w", archive, err)
+		}
+		if err := os.Remove(archive); err != nil {
+			return result, fmt.Errorf("failed to remove nested archive %s: %w", archive, err)
+		}
+
+		// The nested archive is replaced by the files expanded from it
+		result.Files += nested.Files - 1
+		result.Size += nested.Size - info.Size()
+	}
+
+	return result, nil
 }
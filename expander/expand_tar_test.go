@@ -0,0 +1,1025 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package expander
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	gogather "github.com/enterprise-contract/go-gather"
+)
+
+// tarEntry describes an entry to be written to a test tar archive.
+type tarEntry struct {
+	Name    string
+	Content string
+	Dir     bool
+	Mode    int64
+	ModTime time.Time
+}
+
+// createTar writes a tar archive containing the given entries to a temporary directory and returns its path.
+func createTar(t testing.TB, entries []tarEntry) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "archive.tar")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	tw := tar.NewWriter(f)
+	for _, e := range entries {
+		header := &tar.Header{
+			Name:     e.Name,
+			Mode:     0644,
+			Size:     int64(len(e.Content)),
+			Typeflag: tar.TypeReg,
+		}
+		if e.Dir {
+			header.Mode = 0755
+			header.Size = 0
+			header.Typeflag = tar.TypeDir
+		}
+		if e.Mode != 0 {
+			header.Mode = e.Mode
+		}
+		header.ModTime = e.ModTime
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if !e.Dir {
+			if _, err := tw.Write([]byte(e.Content)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// TestTarExpander_Expand tests expanding a tar archive into a directory.
+func TestTarExpander_Expand(t *testing.T) {
+	src := createTar(t, []tarEntry{
+		{Name: "dir/", Dir: true},
+		{Name: "dir/file.txt", Content: "hello"},
+		{Name: "top.txt", Content: "world"},
+	})
+	dst := filepath.Join(t.TempDir(), "out")
+
+	te := &TarExpander{}
+	if err := te.Expand(context.Background(), dst, src, true, 0755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for name, expected := range map[string]string{"dir/file.txt": "hello", "top.txt": "world"} {
+		content, err := os.ReadFile(filepath.Join(dst, name))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+		if string(content) != expected {
+			t.Errorf("unexpected content for %s: got %s, want %s", name, content, expected)
+		}
+	}
+}
+
+// TestTarExpander_Expand_Errors tests that expansion failures wrap the expected sentinel errors.
+func TestTarExpander_Expand_Errors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		entries  []tarEntry
+		expander *TarExpander
+		expected error
+	}{
+		{
+			name:     "size limit",
+			entries:  []tarEntry{{Name: "a.txt", Content: "0123456789"}},
+			expander: &TarExpander{FileSizeLimit: 5},
+			expected: ErrSizeLimitExceeded,
+		},
+		{
+			name:     "files limit",
+			entries:  []tarEntry{{Name: "a.txt", Content: "a"}, {Name: "b.txt", Content: "b"}},
+			expander: &TarExpander{FilesLimit: 1},
+			expected: ErrFilesLimitExceeded,
+		},
+		{
+			name:     "empty archive",
+			entries:  []tarEntry{},
+			expander: &TarExpander{},
+			expected: ErrEmptyArchive,
+		},
+		{
+			name:     "path traversal",
+			entries:  []tarEntry{{Name: "../evil.txt", Content: "evil"}},
+			expander: &TarExpander{},
+			expected: ErrPathTraversal,
+		},
+		{
+			name:     "backslash path traversal",
+			entries:  []tarEntry{{Name: `..\..\evil`, Content: "evil"}},
+			expander: &TarExpander{},
+			expected: ErrPathTraversal,
+		},
+		{
+			name:     "drive letter",
+			entries:  []tarEntry{{Name: `C:\evil`, Content: "evil"}},
+			expander: &TarExpander{},
+			expected: ErrPathTraversal,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			src := createTar(t, tc.entries)
+			err := tc.expander.Expand(context.Background(), filepath.Join(t.TempDir(), "out"), src, true, 0755)
+			if !errors.Is(err, tc.expected) {
+				t.Errorf("expected error wrapping %v, got %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+// TestTarExpander_Expand_FilesLimit tests that an archive with exactly the allowed number of files is expanded.
+func TestTarExpander_Expand_FilesLimit(t *testing.T) {
+	src := createTar(t, []tarEntry{{Name: "a.txt", Content: "a"}, {Name: "b.txt", Content: "b"}})
+
+	te := &TarExpander{FilesLimit: 2}
+	if err := te.Expand(context.Background(), filepath.Join(t.TempDir(), "out"), src, true, 0755); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// TestDefaultNameSanitizer tests the names accepted and rejected by DefaultNameSanitizer.
+func TestDefaultNameSanitizer(t *testing.T) {
+	testCases := []struct {
+		name  string
+		valid bool
+	}{
+		{name: "dir/file.txt", valid: true},
+		{name: ".hidden", valid: true},
+		{name: "console.txt", valid: true},
+		{name: "file\x00.txt", valid: false},
+		{name: "bell\a.txt", valid: false},
+		{name: "CON", valid: false},
+		{name: "dir/con.txt", valid: false},
+		{name: "LPT1.tar.gz", valid: false},
+		{name: "nul/file.txt", valid: false},
+	}
+
+	for _, tc := range testCases {
+		name, err := DefaultNameSanitizer(tc.name)
+		if tc.valid && (err != nil || name != tc.name) {
+			t.Errorf("expected %q to be accepted, got %q, %v", tc.name, name, err)
+		}
+		if !tc.valid && !errors.Is(err, ErrInvalidName) {
+			t.Errorf("expected %q to be rejected with ErrInvalidName, got %v", tc.name, err)
+		}
+	}
+}
+
+// TestTarExpander_Expand_NameSanitizer tests that entry names are sanitized before extraction.
+func TestTarExpander_Expand_NameSanitizer(t *testing.T) {
+	t.Run("control character rejected", func(t *testing.T) {
+		src := createTar(t, []tarEntry{{Name: "bad\x01name.txt", Content: "data"}})
+
+		te := &TarExpander{}
+		err := te.Expand(context.Background(), filepath.Join(t.TempDir(), "out"), src, true, 0755)
+		if !errors.Is(err, ErrInvalidName) {
+			t.Errorf("expected ErrInvalidName, got %v", err)
+		}
+	})
+
+	t.Run("reserved name rejected", func(t *testing.T) {
+		src := createTar(t, []tarEntry{{Name: "CON", Content: "data"}})
+
+		te := &TarExpander{}
+		err := te.Expand(context.Background(), filepath.Join(t.TempDir(), "out"), src, true, 0755)
+		if !errors.Is(err, ErrInvalidName) {
+			t.Errorf("expected ErrInvalidName, got %v", err)
+		}
+	})
+
+	t.Run("custom sanitizer", func(t *testing.T) {
+		src := createTar(t, []tarEntry{{Name: "dir/", Dir: true}, {Name: "dir/File.TXT", Content: "data"}})
+		dst := filepath.Join(t.TempDir(), "out")
+
+		te := &TarExpander{NameSanitizer: func(name string) (string, error) {
+			return strings.ToLower(name), nil
+		}}
+		if err := te.Expand(context.Background(), dst, src, true, 0755); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(dst, "dir", "file.txt")); err != nil {
+			t.Errorf("expected sanitized file to exist: %v", err)
+		}
+	})
+}
+
+// TestTarExpander_Expand_DirsLimit tests the limit on the number of directory entries.
+func TestTarExpander_Expand_DirsLimit(t *testing.T) {
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	src := createTar(t, []tarEntry{
+		{Name: "a/", Dir: true, Mode: 0700, ModTime: modTime},
+		{Name: "a/file.txt", Content: "a"},
+		{Name: "b/", Dir: true, Mode: 0750, ModTime: modTime},
+	})
+
+	t.Run("limit exceeded", func(t *testing.T) {
+		te := &TarExpander{DirsLimit: 1}
+		err := te.Expand(context.Background(), filepath.Join(t.TempDir(), "out"), src, true, 0755)
+		if !errors.Is(err, ErrDirsLimitExceeded) {
+			t.Errorf("expected ErrDirsLimitExceeded, got %v", err)
+		}
+	})
+
+	t.Run("within limit", func(t *testing.T) {
+		dst := filepath.Join(t.TempDir(), "out")
+		te := &TarExpander{DirsLimit: 2}
+		if err := te.Expand(context.Background(), dst, src, true, 0755); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		for name, mode := range map[string]os.FileMode{"a": 0700, "b": 0750} {
+			info, err := os.Stat(filepath.Join(dst, name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != mode {
+				t.Errorf("unexpected mode for %s: got %v, want %v", name, info.Mode().Perm(), mode)
+			}
+			if !info.ModTime().Equal(modTime) {
+				t.Errorf("unexpected modification time for %s: got %v, want %v", name, info.ModTime(), modTime)
+			}
+		}
+	})
+}
+
+// TestTarExpander_Expand_Recursive tests the expansion of archives nested within an archive.
+func TestTarExpander_Expand_Recursive(t *testing.T) {
+	readFile := func(t *testing.T, path string) string {
+		t.Helper()
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	inner := createTar(t, []tarEntry{{Name: "inner.txt", Content: "inner"}})
+	src := createTar(t, []tarEntry{
+		{Name: "outer.txt", Content: "outer"},
+		{Name: "nested/", Dir: true},
+		{Name: "nested/inner.tar", Content: readFile(t, inner)},
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		dst := filepath.Join(t.TempDir(), "out")
+		te := &TarExpander{}
+		if err := te.Expand(context.Background(), dst, src, true, 0755); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(dst, "nested", "inner.tar")); err != nil {
+			t.Errorf("expected nested archive to be left as is: %v", err)
+		}
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		dst := filepath.Join(t.TempDir(), "out")
+		te := &TarExpander{RecursiveExpand: true}
+		if err := te.Expand(context.Background(), dst, src, true, 0755); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := readFile(t, filepath.Join(dst, "outer.txt")); got != "outer" {
+			t.Errorf("unexpected content for outer.txt: got %s, want outer", got)
+		}
+		if got := readFile(t, filepath.Join(dst, "nested", "inner", "inner.txt")); got != "inner" {
+			t.Errorf("unexpected content for inner.txt: got %s, want inner", got)
+		}
+		if _, err := os.Stat(filepath.Join(dst, "nested", "inner.tar")); !os.IsNotExist(err) {
+			t.Errorf("expected nested archive to be removed, got: %v", err)
+		}
+	})
+
+	t.Run("depth limit", func(t *testing.T) {
+		// Wrap the archive in further archives until it is nested deeper than the default limit
+		archive := src
+		for i := 0; i < DefaultMaxDepth; i++ {
+			archive = createTar(t, []tarEntry{{Name: "level.tar", Content: readFile(t, archive)}})
+		}
+
+		te := &TarExpander{RecursiveExpand: true}
+		err := te.Expand(context.Background(), filepath.Join(t.TempDir(), "out"), archive, true, 0755)
+		if !errors.Is(err, ErrMaxDepthExceeded) {
+			t.Errorf("expected ErrMaxDepthExceeded, got %v", err)
+		}
+	})
+
+	t.Run("custom depth limit", func(t *testing.T) {
+		te := &TarExpander{RecursiveExpand: true, MaxDepth: 1}
+		err := te.Expand(context.Background(), filepath.Join(t.TempDir(), "out"), src, true, 0755)
+		if !errors.Is(err, ErrMaxDepthExceeded) {
+			t.Errorf("expected ErrMaxDepthExceeded, got %v", err)
+		}
+	})
+}
+
+// createGzip writes the gzip compressed content to a file with the given name in a temporary directory and returns its path.
+func createGzip(t *testing.T, name string, content []byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	gw := gzip.NewWriter(f)
+	if _, err := gw.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// TestTarExpander_Expand_Gzip tests expanding gzip compressed tar archives.
+func TestTarExpander_Expand_Gzip(t *testing.T) {
+	content, err := os.ReadFile(createTar(t, []tarEntry{{Name: "file.txt", Content: "hello"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"archive.tar.gz", "archive.tgz"} {
+		t.Run(name, func(t *testing.T) {
+			dst := filepath.Join(t.TempDir(), "out")
+			te := &TarExpander{}
+			if err := te.Expand(context.Background(), dst, createGzip(t, name, content), true, 0755); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := os.ReadFile(filepath.Join(dst, "file.txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != "hello" {
+				t.Errorf("unexpected content: got %s, want hello", got)
+			}
+		})
+	}
+
+	t.Run("not a tar archive", func(t *testing.T) {
+		src := createGzip(t, "archive.tar.gz", []byte(strings.Repeat("just some plain text\n", 50)))
+
+		te := &TarExpander{}
+		err := te.Expand(context.Background(), filepath.Join(t.TempDir(), "out"), src, true, 0755)
+		if !errors.Is(err, ErrNotTarArchive) {
+			t.Fatalf("expected ErrNotTarArchive, got %v", err)
+		}
+		if !strings.Contains(err.Error(), "gzip payload is not a tar archive") {
+			t.Errorf("unexpected error message: %v", err)
+		}
+	})
+
+	t.Run("short payload", func(t *testing.T) {
+		src := createGzip(t, "archive.tgz", []byte("short"))
+
+		te := &TarExpander{}
+		err := te.Expand(context.Background(), filepath.Join(t.TempDir(), "out"), src, true, 0755)
+		if !errors.Is(err, ErrNotTarArchive) {
+			t.Errorf("expected ErrNotTarArchive, got %v", err)
+		}
+	})
+
+	t.Run("not gzip compressed", func(t *testing.T) {
+		src := filepath.Join(t.TempDir(), "archive.tar.gz")
+		if err := os.WriteFile(src, content, 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		te := &TarExpander{}
+		if err := te.Expand(context.Background(), filepath.Join(t.TempDir(), "out"), src, true, 0755); err == nil {
+			t.Error("expected an error, got nil")
+		}
+	})
+}
+
+// TestTarExpander_Expand_StripComponents tests removing leading path components from entry names.
+func TestTarExpander_Expand_StripComponents(t *testing.T) {
+	t.Run("single top level directory", func(t *testing.T) {
+		src := createTar(t, []tarEntry{
+			{Name: "project-1.2.3/", Dir: true},
+			{Name: "project-1.2.3/README.md", Content: "readme"},
+			{Name: "project-1.2.3/src/", Dir: true},
+			{Name: "project-1.2.3/src/main.go", Content: "main"},
+		})
+		dst := filepath.Join(t.TempDir(), "out")
+
+		te := &TarExpander{StripComponents: 1}
+		if err := te.Expand(context.Background(), dst, src, true, 0755); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		for name, expected := range map[string]string{"README.md": "readme", "src/main.go": "main"} {
+			content, err := os.ReadFile(filepath.Join(dst, name))
+			if err != nil {
+				t.Fatalf("failed to read %s: %v", name, err)
+			}
+			if string(content) != expected {
+				t.Errorf("unexpected content for %s: got %s, want %s", name, content, expected)
+			}
+		}
+		if _, err := os.Stat(filepath.Join(dst, "project-1.2.3")); !os.IsNotExist(err) {
+			t.Errorf("expected top level directory to be stripped, got: %v", err)
+		}
+	})
+
+	t.Run("entries with too few components are skipped", func(t *testing.T) {
+		src := createTar(t, []tarEntry{
+			{Name: "top.txt", Content: "top"},
+			{Name: "dir/file.txt", Content: "file"},
+		})
+		dst := filepath.Join(t.TempDir(), "out")
+
+		te := &TarExpander{StripComponents: 1}
+		if err := te.Expand(context.Background(), dst, src, true, 0755); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		entries, err := os.ReadDir(dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 || entries[0].Name() != "file.txt" {
+			t.Errorf("expected only file.txt to be extracted, got %v", entries)
+		}
+	})
+
+	t.Run("path traversal after stripping", func(t *testing.T) {
+		src := createTar(t, []tarEntry{{Name: "top/../../evil.txt", Content: "evil"}})
+
+		te := &TarExpander{StripComponents: 1}
+		err := te.Expand(context.Background(), filepath.Join(t.TempDir(), "out"), src, true, 0755)
+		if !errors.Is(err, ErrPathTraversal) {
+			t.Errorf("expected ErrPathTraversal, got %v", err)
+		}
+	})
+}
+
+// TestHasExtension tests that extensions are matched regardless of case.
+func TestHasExtension(t *testing.T) {
+	testCases := []struct {
+		path     string
+		ext      string
+		expected bool
+	}{
+		{path: "archive.tar", ext: ".tar", expected: true},
+		{path: "ARCHIVE.TAR", ext: ".tar", expected: true},
+		{path: "Archive.Tar.Gz", ext: ".tar.gz", expected: true},
+		{path: "archive.TGZ", ext: ".tgz", expected: true},
+		{path: "archive.tar.gz.bak", ext: ".tar.gz", expected: false},
+		{path: "tar", ext: ".tar", expected: false},
+	}
+
+	for _, tc := range testCases {
+		if got := hasExtension(tc.path, tc.ext); got != tc.expected {
+			t.Errorf("hasExtension(%q, %q) = %v, want %v", tc.path, tc.ext, got, tc.expected)
+		}
+	}
+}
+
+// TestTarExpander_Expand_MixedCaseExtensions tests that archives with upper and mixed case extensions are expanded.
+func TestTarExpander_Expand_MixedCaseExtensions(t *testing.T) {
+	content, err := os.ReadFile(createTar(t, []tarEntry{{Name: "file.txt", Content: "hello"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("gzip", func(t *testing.T) {
+		for _, name := range []string{"ARCHIVE.TAR.GZ", "Archive.Tgz"} {
+			dst := filepath.Join(t.TempDir(), "out")
+			te := &TarExpander{}
+			if err := te.Expand(context.Background(), dst, createGzip(t, name, content), true, 0755); err != nil {
+				t.Fatalf("unexpected error for %s: %v", name, err)
+			}
+			if _, err := os.Stat(filepath.Join(dst, "file.txt")); err != nil {
+				t.Errorf("expected file.txt to be extracted from %s: %v", name, err)
+			}
+		}
+	})
+
+	t.Run("nested", func(t *testing.T) {
+		src := createTar(t, []tarEntry{{Name: "INNER.TAR", Content: string(content)}})
+		dst := filepath.Join(t.TempDir(), "out")
+
+		te := &TarExpander{RecursiveExpand: true}
+		if err := te.Expand(context.Background(), dst, src, true, 0755); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(dst, "INNER", "file.txt")); err != nil {
+			t.Errorf("expected nested archive to be expanded: %v", err)
+		}
+	})
+}
+
+// BenchmarkTarExpander_Expand benchmarks expanding many small archives in sequence.
+func BenchmarkTarExpander_Expand(b *testing.B) {
+	entries := make([]tarEntry, 0, 20)
+	for i := 0; i < cap(entries); i++ {
+		entries = append(entries, tarEntry{Name: fmt.Sprintf("file%d.txt", i), Content: strings.Repeat("x", 1024)})
+	}
+	src := createTar(b, entries)
+	dst := b.TempDir()
+	te := &TarExpander{}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := te.Expand(context.Background(), dst, src, true, 0755); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// TestTarExpander_Expand_ContextCanceled tests that expansion stops once the context is canceled.
+func TestTarExpander_Expand_ContextCanceled(t *testing.T) {
+	entries := make([]tarEntry, 0, 100)
+	for i := 0; i < cap(entries); i++ {
+		entries = append(entries, tarEntry{Name: fmt.Sprintf("file%d.txt", i), Content: "data"})
+	}
+	src := createTar(t, entries)
+
+	t.Run("canceled before expansion", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		te := &TarExpander{}
+		err := te.Expand(ctx, filepath.Join(t.TempDir(), "out"), src, true, 0755)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+	})
+
+	t.Run("canceled during expansion", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		// Cancel the context once a few entries have been seen
+		seen := 0
+		te := &TarExpander{NameSanitizer: func(name string) (string, error) {
+			seen++
+			if seen == 10 {
+				cancel()
+			}
+			return name, nil
+		}}
+
+		dst := filepath.Join(t.TempDir(), "out")
+		err := te.Expand(ctx, dst, src, true, 0755)
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+
+		extracted, err := os.ReadDir(dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(extracted) >= len(entries) {
+			t.Errorf("expected expansion to stop early, but %d entries were extracted", len(extracted))
+		}
+	})
+}
+
+// TestTarExpander_ExpandReader tests expanding tar archives read from a stream.
+func TestTarExpander_ExpandReader(t *testing.T) {
+	content, err := os.ReadFile(createTar(t, []tarEntry{{Name: "dir/", Dir: true}, {Name: "dir/file.txt", Content: "hello"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed, err := os.ReadFile(createGzip(t, "archive.tar.gz", content))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, stream := range map[string][]byte{"tar": content, "gzip compressed tar": compressed} {
+		t.Run(name, func(t *testing.T) {
+			dst := filepath.Join(t.TempDir(), "out")
+			te := &TarExpander{}
+			if err := te.ExpandReader(context.Background(), bytes.NewReader(stream), dst, 0755); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := os.ReadFile(filepath.Join(dst, "dir", "file.txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != "hello" {
+				t.Errorf("unexpected content: got %s, want hello", got)
+			}
+		})
+	}
+
+	t.Run("gzip compressed text", func(t *testing.T) {
+		text, err := os.ReadFile(createGzip(t, "file.gz", []byte(strings.Repeat("text\n", 200))))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		te := &TarExpander{}
+		err = te.ExpandReader(context.Background(), bytes.NewReader(text), filepath.Join(t.TempDir(), "out"), 0755)
+		if !errors.Is(err, ErrNotTarArchive) {
+			t.Errorf("expected ErrNotTarArchive, got %v", err)
+		}
+	})
+}
+
+// TestTarExpander_Expand_IncludeExclude tests extracting only the entries selected by glob patterns.
+func TestTarExpander_Expand_IncludeExclude(t *testing.T) {
+	src := createTar(t, []tarEntry{
+		{Name: "top.txt", Content: "top"},
+		{Name: "image.png", Content: "png"},
+		{Name: "policy/", Dir: true},
+		{Name: "policy/main.rego", Content: "rego"},
+		{Name: "policy/notes.txt", Content: "notes"},
+		{Name: "docs/", Dir: true},
+		{Name: "docs/guide.txt", Content: "guide"},
+	})
+
+	testCases := []struct {
+		name     string
+		include  []string
+		exclude  []string
+		expected []string
+	}{
+		{
+			name:     "include by extension",
+			include:  []string{"*.txt"},
+			expected: []string{"top.txt", "policy/notes.txt", "docs/guide.txt"},
+		},
+		{
+			name:     "include directory",
+			include:  []string{"policy/**"},
+			expected: []string{"policy/main.rego", "policy/notes.txt"},
+		},
+		{
+			name:     "exclude",
+			exclude:  []string{"*.png", "docs/**"},
+			expected: []string{"top.txt", "policy/main.rego", "policy/notes.txt"},
+		},
+		{
+			name:     "include and exclude",
+			include:  []string{"*.txt"},
+			exclude:  []string{"docs/*"},
+			expected: []string{"top.txt", "policy/notes.txt"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dst := filepath.Join(t.TempDir(), "out")
+			te := &TarExpander{Include: tc.include, Exclude: tc.exclude}
+			if err := te.Expand(context.Background(), dst, src, true, 0755); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var extracted []string
+			err := filepath.Walk(dst, func(path string, info os.FileInfo, err error) error {
+				if err == nil && !info.IsDir() {
+					rel, _ := filepath.Rel(dst, path)
+					extracted = append(extracted, filepath.ToSlash(rel))
+				}
+				return err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(extracted) != len(tc.expected) {
+				t.Fatalf("expected %v to be extracted, got %v", tc.expected, extracted)
+			}
+			for _, name := range tc.expected {
+				if _, err := os.Stat(filepath.Join(dst, name)); err != nil {
+					t.Errorf("expected %s to be extracted: %v", name, err)
+				}
+			}
+		})
+	}
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		te := &TarExpander{Include: []string{"[*.txt"}}
+		if err := te.Expand(context.Background(), filepath.Join(t.TempDir(), "out"), src, true, 0755); err == nil {
+			t.Error("expected an error, got nil")
+		}
+	})
+
+	t.Run("path traversal is still rejected", func(t *testing.T) {
+		src := createTar(t, []tarEntry{{Name: "../evil.txt", Content: "evil"}})
+		te := &TarExpander{Exclude: []string{"*.txt"}}
+		err := te.Expand(context.Background(), filepath.Join(t.TempDir(), "out"), src, true, 0755)
+		if !errors.Is(err, ErrPathTraversal) {
+			t.Errorf("expected ErrPathTraversal, got %v", err)
+		}
+	})
+}
+
+func TestTarExpander_Expand_Clock(t *testing.T) {
+	fixed := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	original := gogather.NowFunc
+	gogather.NowFunc = func() time.Time { return fixed }
+	t.Cleanup(func() { gogather.NowFunc = original })
+
+	modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	src := createTar(t, []tarEntry{
+		{Name: "stamped.txt", Content: "a", ModTime: modTime},
+		{Name: "unstamped.txt", Content: "b"},
+	})
+
+	dst := t.TempDir()
+	if err := (&TarExpander{}).Expand(context.Background(), dst, src, true, 0755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for name, expected := range map[string]time.Time{"stamped.txt": modTime, "unstamped.txt": fixed} {
+		info, err := os.Stat(filepath.Join(dst, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !info.ModTime().Equal(expected) {
+			t.Errorf("expected %s to be modified at %s, got %s", name, expected, info.ModTime())
+		}
+	}
+}
+
+// TestTarExpander_Expand_EnvLimits tests that the limits set in the environment apply to expanders without their own.
+func TestTarExpander_Expand_EnvLimits(t *testing.T) {
+	src := createTar(t, []tarEntry{{Name: "a.txt", Content: "aaaa"}, {Name: "b.txt", Content: "bbbb"}})
+
+	testCases := []struct {
+		name     string
+		env      map[string]string
+		expander *TarExpander
+		expected error
+	}{
+		{name: "size limit", env: map[string]string{MaxFileSizeEnv: "6"}, expander: &TarExpander{}, expected: ErrSizeLimitExceeded},
+		{name: "files limit", env: map[string]string{MaxFilesEnv: "1"}, expander: &TarExpander{}, expected: ErrFilesLimitExceeded},
+		{name: "within limits", env: map[string]string{MaxFileSizeEnv: "8", MaxFilesEnv: "2"}, expander: &TarExpander{}},
+		{name: "expander limits take precedence", env: map[string]string{MaxFileSizeEnv: "6", MaxFilesEnv: "1"}, expander: &TarExpander{FileSizeLimit: 8, FilesLimit: 2}},
+		{name: "base expanders", env: map[string]string{MaxFilesEnv: "1"}, expander: BaseExpanders(0, 0)["tar"].(*TarExpander), expected: ErrFilesLimitExceeded},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+			err := tc.expander.Expand(context.Background(), filepath.Join(t.TempDir(), "out"), src, true, 0755)
+			if !errors.Is(err, tc.expected) {
+				t.Errorf("expected error %v, got %v", tc.expected, err)
+			}
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Setenv(MaxFilesEnv, "many")
+		err := (&TarExpander{}).Expand(context.Background(), filepath.Join(t.TempDir(), "out"), src, true, 0755)
+		if err == nil || !strings.Contains(err.Error(), MaxFilesEnv) {
+			t.Errorf("expected an error about %s, got %v", MaxFilesEnv, err)
+		}
+	})
+}
+
+// TestGetExpander tests that the expander returned for an archive enforces the given limits.
+func TestGetExpander(t *testing.T) {
+	src := createTar(t, []tarEntry{{Name: "a.txt", Content: "aaaa"}, {Name: "b.txt", Content: "bbbb"}})
+
+	testCases := []struct {
+		name          string
+		filesLimit    int
+		fileSizeLimit int64
+		expected      error
+	}{
+		{name: "files limit", filesLimit: 1, expected: ErrFilesLimitExceeded},
+		{name: "size limit", fileSizeLimit: 7, expected: ErrSizeLimitExceeded},
+		{name: "within limits", filesLimit: 2, fileSizeLimit: 8},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e, err := GetExpander(src, tc.filesLimit, tc.fileSizeLimit)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			err = e.Expand(context.Background(), filepath.Join(t.TempDir(), "out"), src, true, 0755)
+			if !errors.Is(err, tc.expected) {
+				t.Errorf("expected error %v, got %v", tc.expected, err)
+			}
+		})
+	}
+
+	t.Run("unsupported", func(t *testing.T) {
+		if _, err := GetExpander("archive.zip", 0, 0); !errors.Is(err, ErrUnsupportedArchive) {
+			t.Errorf("expected ErrUnsupportedArchive, got %v", err)
+		}
+	})
+}
+
+// TestTarExpander_ExpandWithResult tests the number and size of the files reported as extracted.
+func TestTarExpander_ExpandWithResult(t *testing.T) {
+	t.Run("flat", func(t *testing.T) {
+		src := createTar(t, []tarEntry{{Name: "dir/", Dir: true}, {Name: "dir/a.txt", Content: "aaa"}, {Name: "b.txt", Content: "bbbb"}})
+		result, err := (&TarExpander{}).ExpandWithResult(context.Background(), filepath.Join(t.TempDir(), "out"), src, true, 0755)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result != (ExpandResult{Files: 2, Size: 7}) {
+			t.Errorf("unexpected result: %+v", result)
+		}
+	})
+
+	t.Run("recursive", func(t *testing.T) {
+		nested, err := os.ReadFile(createTar(t, []tarEntry{{Name: "c.txt", Content: "cc"}, {Name: "d.txt", Content: "dddd"}}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		src := createTar(t, []tarEntry{{Name: "a.txt", Content: "aaa"}, {Name: "nested.tar", Content: string(nested)}})
+
+		result, err := (&TarExpander{RecursiveExpand: true}).ExpandWithResult(context.Background(), filepath.Join(t.TempDir(), "out"), src, true, 0755)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result != (ExpandResult{Files: 3, Size: 9}) {
+			t.Errorf("unexpected result: %+v", result)
+		}
+	})
+}
+
+// TestTarExpander_Expand_LongNames tests that names too long for a ustar header, stored in PAX records or
+// GNU long name entries, are extracted in full.
+func TestTarExpander_Expand_LongNames(t *testing.T) {
+	name := strings.Repeat("d", 60) + "/" + strings.Repeat("f", 80) + ".txt"
+
+	for _, format := range []tar.Format{tar.FormatPAX, tar.FormatGNU} {
+		t.Run(format.String(), func(t *testing.T) {
+			src := filepath.Join(t.TempDir(), "archive.tar")
+			f, err := os.Create(src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tw := tar.NewWriter(f)
+			header := &tar.Header{Name: name, Mode: 0644, Size: 5, Typeflag: tar.TypeReg, Format: format}
+			if err := tw.WriteHeader(header); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := tw.Write([]byte("hello")); err != nil {
+				t.Fatal(err)
+			}
+			if err := tw.Close(); err != nil {
+				t.Fatal(err)
+			}
+			f.Close()
+
+			dst := filepath.Join(t.TempDir(), "out")
+			if err := (&TarExpander{}).Expand(context.Background(), dst, src, true, 0755); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			content, err := os.ReadFile(filepath.Join(dst, name))
+			if err != nil || string(content) != "hello" {
+				t.Errorf("expected %s to be extracted with its full name, but got %q, %v", name, content, err)
+			}
+		})
+	}
+}
+
+// TestTarExpander_Expand_SymlinkDestination tests expanding into a destination that is a symbolic link.
+func TestTarExpander_Expand_SymlinkDestination(t *testing.T) {
+	src := createTar(t, []tarEntry{{Name: "file.txt", Content: "hello"}})
+
+	target := t.TempDir()
+	dst := filepath.Join(t.TempDir(), "link")
+	if err := os.Symlink(target, dst); err != nil {
+		t.Skipf("symbolic links are not supported: %v", err)
+	}
+
+	err := (&TarExpander{RejectSymlinkDestination: true}).Expand(context.Background(), dst, src, true, 0755)
+	if !errors.Is(err, ErrSymlinkDestination) {
+		t.Fatalf("expected ErrSymlinkDestination, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(target, "file.txt")); !os.IsNotExist(err) {
+		t.Errorf("expected nothing to be extracted into the link target, got %v", err)
+	}
+
+	if err := (&TarExpander{}).Expand(context.Background(), dst, src, true, 0755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(target, "file.txt")); err != nil {
+		t.Errorf("expected the archive to be extracted into the link target: %v", err)
+	}
+}
+
+func TestTarExpander_Expand_ClampModTime(t *testing.T) {
+	fixed := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	original := gogather.NowFunc
+	gogather.NowFunc = func() time.Time { return fixed }
+	t.Cleanup(func() { gogather.NowFunc = original })
+
+	past := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	future := time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC)
+	src := createTar(t, []tarEntry{
+		{Name: "dir/", Dir: true, Mode: 0755, ModTime: future},
+		{Name: "dir/future.txt", Content: "a", ModTime: future},
+		{Name: "past.txt", Content: "b", ModTime: past},
+	})
+
+	limit := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		expander *TarExpander
+		expected map[string]time.Time
+	}{
+		{"disabled", &TarExpander{}, map[string]time.Time{"dir": future, "dir/future.txt": future, "past.txt": past}},
+		{"extraction time", &TarExpander{ClampModTime: true}, map[string]time.Time{"dir": fixed, "dir/future.txt": fixed, "past.txt": past}},
+		{"max", &TarExpander{ClampModTime: true, MaxModTime: limit}, map[string]time.Time{"dir": limit, "dir/future.txt": limit, "past.txt": past}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := t.TempDir()
+			if err := tt.expander.Expand(context.Background(), dst, src, true, 0755); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for name, expected := range tt.expected {
+				info, err := os.Stat(filepath.Join(dst, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !info.ModTime().Equal(expected) {
+					t.Errorf("expected %s to be modified at %s, got %s", name, expected, info.ModTime())
+				}
+			}
+		})
+	}
+}
+
+// TestTarExpander_Expand_RecursiveLimits tests that the limits apply to all the archives of a recursive expansion together.
+func TestTarExpander_Expand_RecursiveLimits(t *testing.T) {
+	content := strings.Repeat("x", 100)
+	inner := createTar(t, []tarEntry{{Name: "dir/", Dir: true}, {Name: "dir/a.txt", Content: content}, {Name: "b.txt", Content: content}})
+	innerContent, err := os.ReadFile(inner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := createTar(t, []tarEntry{{Name: "dir/", Dir: true}, {Name: "dir/inner.tar", Content: string(innerContent)}, {Name: "c.txt", Content: content}})
+
+	// Each archive is within these limits on its own, but not with the other
+	outerSize := int64(len(innerContent) + len(content))
+	testCases := []struct {
+		name     string
+		expander *TarExpander
+		expected error
+	}{
+		{"size limit", &TarExpander{RecursiveExpand: true, FileSizeLimit: outerSize + 1}, ErrSizeLimitExceeded},
+		{"files limit", &TarExpander{RecursiveExpand: true, FilesLimit: 3}, ErrFilesLimitExceeded},
+		{"dirs limit", &TarExpander{RecursiveExpand: true, DirsLimit: 1}, ErrDirsLimitExceeded},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := (&TarExpander{RecursiveExpand: false, FileSizeLimit: tc.expander.FileSizeLimit, FilesLimit: tc.expander.FilesLimit, DirsLimit: tc.expander.DirsLimit}).Expand(context.Background(), t.TempDir(), src, true, 0755); err != nil {
+				t.Fatalf("expected the outer archive alone to be within the limits, got: %v", err)
+			}
+
+			err := tc.expander.Expand(context.Background(), filepath.Join(t.TempDir(), "out"), src, true, 0755)
+			if !errors.Is(err, tc.expected) {
+				t.Errorf("expected error wrapping %v, got %v", tc.expected, err)
+			}
+		})
+	}
+}
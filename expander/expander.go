@@ -17,22 +17,159 @@
 package expander
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"unicode"
 )
 
+var (
+	// ErrSizeLimitExceeded is returned when the expanded content exceeds the configured size limit.
+	ErrSizeLimitExceeded = errors.New("size limit exceeded")
+	// ErrFilesLimitExceeded is returned when an archive contains more files than the configured limit.
+	ErrFilesLimitExceeded = errors.New("files limit exceeded")
+	// ErrDirsLimitExceeded is returned when an archive contains more directories than the configured limit.
+	ErrDirsLimitExceeded = errors.New("directories limit exceeded")
+	// ErrEmptyArchive is returned when an archive contains no files.
+	ErrEmptyArchive = errors.New("archive is empty")
+	// ErrPathTraversal is returned when an archive entry would be written outside of the destination directory.
+	ErrPathTraversal = errors.New("archive entry escapes destination directory")
+	// ErrInvalidName is returned when the name of an archive entry is rejected by a name sanitizer.
+	ErrInvalidName = errors.New("invalid archive entry name")
+	// ErrMaxDepthExceeded is returned when recursively expanded archives are nested deeper than the configured limit.
+	ErrMaxDepthExceeded = errors.New("maximum archive nesting depth exceeded")
+	// ErrNotTarArchive is returned when the decompressed content of a compressed tar archive is not a tar archive.
+	ErrNotTarArchive = errors.New("gzip payload is not a tar archive")
+	// ErrUnsupportedArchive is returned by GetExpander when no expander handles the source.
+	ErrUnsupportedArchive = errors.New("unsupported archive")
+	// ErrSymlinkDestination is returned when the destination is a symbolic link and RejectSymlinkDestination is set.
+	ErrSymlinkDestination = errors.New("destination is a symbolic link")
+)
+
+const (
+	// MaxFileSizeEnv is the environment variable that sets the size limit used by expanders with no FileSizeLimit.
+	MaxFileSizeEnv = "GO_GATHER_MAX_FILE_SIZE"
+	// MaxFilesEnv is the environment variable that sets the files limit used by expanders with no FilesLimit.
+	MaxFilesEnv = "GO_GATHER_MAX_FILES"
+)
+
+// DefaultMaxDepth is the maximum nesting depth of archives expanded recursively when none is configured.
+const DefaultMaxDepth = 3
+
+// reservedNames are the device names reserved on Windows, which cannot be used as a file name regardless of extension.
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
 // Expander is an interface which defines the methods that an expander must implement in order expand a type
 type Expander interface {
-	Expand(src, dst string, dir bool, mode os.FileMode) error
+	Expand(ctx context.Context, src, dst string, dir bool, mode os.FileMode) error
 }
 
 // BaseExpanders creates the set of base expanders that are used to expand the different types of files
 func BaseExpanders(filesLimit int, fileSizeLimit int64) map[string]Expander {
 	return map[string]Expander{
-		"tar": &TarExpander{},
+		"tar": &TarExpander{FilesLimit: filesLimit, FileSizeLimit: fileSizeLimit},
+	}
+}
+
+// GetExpander returns the base expander for the archive at src, chosen by its extension, configured with the
+// given limits. Zero limits fall back to those set in the environment, see MaxFileSizeEnv and MaxFilesEnv.
+func GetExpander(src string, filesLimit int, fileSizeLimit int64) (Expander, error) {
+	if !IsArchive(src) {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedArchive, src)
+	}
+	return BaseExpanders(filesLimit, fileSizeLimit)["tar"], nil
+}
+
+// defaultLimits returns the size and files limits set by the MaxFileSizeEnv and MaxFilesEnv environment variables,
+// which are zero, meaning no limit, if the variables are not set.
+func defaultLimits() (fileSizeLimit int64, filesLimit int, err error) {
+	if v := os.Getenv(MaxFileSizeEnv); v != "" {
+		if fileSizeLimit, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return 0, 0, fmt.Errorf("invalid %s: %w", MaxFileSizeEnv, err)
+		}
+	}
+	if v := os.Getenv(MaxFilesEnv); v != "" {
+		if filesLimit, err = strconv.Atoi(v); err != nil {
+			return 0, 0, fmt.Errorf("invalid %s: %w", MaxFilesEnv, err)
+		}
+	}
+	return fileSizeLimit, filesLimit, nil
+}
+
+// archiveExtensions are the file extensions of the archives that are expanded recursively
+var archiveExtensions = []string{".tar", ".tar.gz", ".tgz"}
+
+// IsArchive reports whether the file at path has the extension of an archive that can be expanded, ignoring case
+func IsArchive(path string) bool {
+	return archiveDestination(path) != path
+}
+
+// archiveDestination returns the directory into which the nested archive at path is expanded,
+// which is the path with its archive extension removed
+func archiveDestination(path string) string {
+	for _, ext := range archiveExtensions {
+		if hasExtension(path, ext) {
+			return path[:len(path)-len(ext)]
+		}
+	}
+	return path
+}
+
+// hasExtension reports whether path ends with the extension ext, ignoring case
+func hasExtension(path, ext string) bool {
+	return len(path) >= len(ext) && strings.EqualFold(path[len(path)-len(ext):], ext)
+}
+
+// selectEntry reports whether an archive entry with the given name is extracted, given the include and exclude
+// glob patterns. A pattern without a "/" is matched against the base name of the entry, e.g. "*.rego", a pattern
+// ending with "/**" matches everything within a directory, e.g. "policy/**", and any other pattern is matched
+// against the whole name using path.Match.
+func selectEntry(name string, include, exclude []string) (bool, error) {
+	name = strings.TrimSuffix(filepath.ToSlash(name), "/")
+
+	for _, pattern := range exclude {
+		matched, err := matchEntry(pattern, name)
+		if err != nil || matched {
+			return false, err
+		}
+	}
+
+	if len(include) == 0 {
+		return true, nil
+	}
+	for _, pattern := range include {
+		matched, err := matchEntry(pattern, name)
+		if err != nil || matched {
+			return matched, err
+		}
 	}
+	return false, nil
+}
+
+// matchEntry reports whether the entry name matches the glob pattern, see selectEntry
+func matchEntry(pattern, name string) (bool, error) {
+	if dir, ok := strings.CutSuffix(pattern, "/**"); ok {
+		return name == dir || strings.HasPrefix(name, dir+"/"), nil
+	}
+	if !strings.Contains(pattern, "/") {
+		name = path.Base(name)
+	}
+	matched, err := path.Match(pattern, name)
+	if err != nil {
+		return false, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	return matched, nil
 }
 
 // containsDotDot checks if the filepath value v contains a ".." entry.
@@ -50,10 +187,57 @@ func containsDotDot(v string) bool {
 	return false
 }
 
+// hasDriveLetter reports whether v starts with a Windows drive letter, such as "C:", which would make
+// it an absolute path, or one relative to another drive, when joined to the destination on Windows
+func hasDriveLetter(v string) bool {
+	return len(v) >= 2 && v[1] == ':' && ('a' <= v[0] && v[0] <= 'z' || 'A' <= v[0] && v[0] <= 'Z')
+}
+
 func isSlash(r rune) bool { return r == '/' || r == '\\' }
 
-// copyReader copies a reader to a file. If fileSizeLimit is greater than 0, it will limit the size of the file.
-func copyReader(src io.Reader, dst string, mode os.FileMode, fileSizeLimit int64) error {
+// DefaultNameSanitizer is the name sanitizer used by expanders when none is configured.
+// It rejects entry names containing control characters, and names with a path component
+// that is a reserved device name on Windows (e.g. CON, NUL or COM1, with or without an extension).
+func DefaultNameSanitizer(name string) (string, error) {
+	for _, r := range name {
+		if unicode.IsControl(r) {
+			return "", fmt.Errorf("%w: %q contains a control character", ErrInvalidName, name)
+		}
+	}
+
+	for _, component := range strings.FieldsFunc(name, isSlash) {
+		base, _, _ := strings.Cut(component, ".")
+		if reservedNames[strings.ToUpper(base)] {
+			return "", fmt.Errorf("%w: %q contains the reserved name %s", ErrInvalidName, name, component)
+		}
+	}
+
+	return name, nil
+}
+
+// copyBufferPool holds the buffers used to copy archive entries, shared across expansions to reduce allocations
+var copyBufferPool = sync.Pool{
+	New: func() any {
+		buf := make([]byte, 32*1024)
+		return &buf
+	},
+}
+
+// contextReader is a reader that stops reading once its context is done
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+// copyReader copies a reader to a file, stopping if the context is done. If fileSizeLimit is greater than 0, it will limit the size of the file.
+func copyReader(ctx context.Context, src io.Reader, dst string, mode os.FileMode, fileSizeLimit int64) error {
 	dstF, err := os.OpenFile(dst, os.O_RDWR|os.O_CREATE|os.O_TRUNC, mode)
 	if err != nil {
 		return fmt.Errorf("failed to open file %s: %w", dst, err)
@@ -63,8 +247,13 @@ func copyReader(src io.Reader, dst string, mode os.FileMode, fileSizeLimit int64
 	if fileSizeLimit > 0 {
 		src = io.LimitReader(src, fileSizeLimit)
 	}
+	src = &contextReader{ctx: ctx, r: src}
+
+	buf := copyBufferPool.Get().(*[]byte)
+	defer copyBufferPool.Put(buf)
 
-	_, err = io.Copy(dstF, src)
+	// Hide the file's ReadFrom method, which would otherwise allocate its own buffer for non-file readers
+	_, err = io.CopyBuffer(struct{ io.Writer }{dstF}, src, *buf)
 	if err != nil {
 		return fmt.Errorf("failed to copy file %s: %w", dst, err)
 	}
@@ -1,3 +1,5 @@
 module github.com/enterprise-contract/go-gather/expander
 
 go 1.21.9
+
+require github.com/enterprise-contract/go-gather v0.0.1
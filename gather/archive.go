@@ -0,0 +1,246 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package gather
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	gogather "github.com/enterprise-contract/go-gather"
+	"github.com/enterprise-contract/go-gather/metadata"
+	fileMetadata "github.com/enterprise-contract/go-gather/metadata/file"
+)
+
+// isArchiveDestination reports whether the destination is the path of an archive to pack the gathered content into
+func isArchiveDestination(destination string) bool {
+	d := strings.ToLower(destination)
+	return strings.HasSuffix(d, ".tar") || strings.HasSuffix(d, ".tar.gz") || strings.HasSuffix(d, ".tgz") ||
+		strings.HasSuffix(d, ".zip")
+}
+
+// gathersDirectory reports whether gathering the source produces a directory, rather than a single file
+func gathersDirectory(srcProtocol gogather.URIType, source string) bool {
+	switch srcProtocol {
+	case gogather.GitURI, gogather.OCIURI:
+		return true
+	case gogather.FileURI:
+		src, err := url.Parse(strings.TrimPrefix(source, "file::"))
+		if err != nil {
+			return false
+		}
+		info, err := os.Stat(gogather.ExpandTilde(src.Path))
+		return err == nil && info.IsDir()
+	}
+	return false
+}
+
+// gatherToArchive gathers the source into a temporary directory and packs its content into an archive at the destination
+func gatherToArchive(ctx context.Context, gatherer Gatherer, srcProtocol gogather.URIType, source, destination string) (metadata.Metadata, error) {
+	tmpDir, err := os.MkdirTemp("", "go-gather-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	content := filepath.Join(tmpDir, "content")
+	target := content
+	if srcProtocol == gogather.FileURI {
+		// The file gatherer saves files to a destination URI
+		target = "file://" + content
+	}
+
+	if _, err := gatherer.Gather(ctx, source, target); err != nil {
+		return nil, err
+	}
+
+	destination = gogather.ExpandTilde(destination)
+	if err := packArchive(content, destination); err != nil {
+		return nil, fmt.Errorf("failed to pack archive: %w", err)
+	}
+
+	info, err := os.Stat(destination)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file info: %w", err)
+	}
+
+	sha, err := fileSha(destination)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileMetadata.FileMetadata{
+		Size:      info.Size(),
+		Path:      destination,
+		Timestamp: gogather.NowFunc(),
+		SHA:       sha,
+	}, nil
+}
+
+// packArchive writes the content of the src directory to an archive at dst: a zip archive if dst ends with ".zip",
+// otherwise a tar archive, gzip compressed if dst ends with ".gz" or ".tgz"
+func packArchive(src, dst string) (err error) {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	d := strings.ToLower(dst)
+	if strings.HasSuffix(d, ".zip") {
+		return packZip(src, f)
+	}
+
+	var w io.Writer = f
+	if strings.HasSuffix(d, ".gz") || strings.HasSuffix(d, ".tgz") {
+		gw := gzip.NewWriter(f)
+		defer func() {
+			if cerr := gw.Close(); err == nil {
+				err = cerr
+			}
+		}()
+		w = gw
+	}
+
+	tw := tar.NewWriter(w)
+	defer func() {
+		if cerr := tw.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	return walkContent(src, func(path, name string, info os.FileInfo) error {
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = name
+
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		return copyFile(tw, path)
+	})
+}
+
+// packZip writes the content of the src directory to a zip archive in w
+func packZip(src string, w io.Writer) (err error) {
+	zw := zip.NewWriter(w)
+	defer func() {
+		if cerr := zw.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	return walkContent(src, func(path, name string, info os.FileInfo) error {
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = name
+		if !info.IsDir() {
+			header.Method = zip.Deflate
+		}
+
+		fw, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		return copyFile(fw, path)
+	})
+}
+
+// walkContent calls fn for each directory and regular file within src, other than the metadata of git repositories,
+// with the name of its archive entry: its slash separated path relative to src, ending with a slash for a directory
+func walkContent(src string, fn func(path, name string, info os.FileInfo) error) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		// Skip the root directory, and the metadata of git repositories
+		if rel == "." {
+			return nil
+		}
+		if rel == ".git" && info.IsDir() {
+			return filepath.SkipDir
+		}
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			return nil
+		}
+
+		name := filepath.ToSlash(rel)
+		if info.IsDir() {
+			name += "/"
+		}
+		return fn(path, name, info)
+	})
+}
+
+// copyFile copies the content of the file at path to w
+func copyFile(w io.Writer, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(w, file)
+	return err
+}
+
+// fileSha calculates the SHA256 hash of the file at the given path
+func fileSha(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return "", fmt.Errorf("failed to calculate file SHA: %w", err)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
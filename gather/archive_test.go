@@ -0,0 +1,185 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package gather
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/enterprise-contract/go-gather/metadata/file"
+)
+
+// readArchive returns the content of the regular files in the tar or zip archive at path, keyed by name.
+func readArchive(t *testing.T, path string) map[string]string {
+	t.Helper()
+
+	if strings.HasSuffix(path, ".zip") {
+		return readZip(t, path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") || strings.HasSuffix(path, ".tgz") {
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer gr.Close()
+		r = gr
+	}
+
+	files := map[string]string{}
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[header.Name] = string(content)
+	}
+	return files
+}
+
+// readZip returns the content of the files in the zip archive at path, keyed by name.
+func readZip(t *testing.T, path string) map[string]string {
+	t.Helper()
+
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+
+	files := map[string]string{}
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(content)
+	}
+	return files
+}
+
+func TestGather_ArchiveDestination(t *testing.T) {
+	ctx := context.Background()
+
+	source := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(source, "policy"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(source, "README.md"), []byte("readme"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(source, "policy", "main.rego"), []byte("package main"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"out.tar.gz", "out.tgz", "out.tar", "out.zip"} {
+		t.Run(name, func(t *testing.T) {
+			destination := filepath.Join(t.TempDir(), name)
+			m, err := Gather(ctx, source, destination)
+			if err != nil {
+				t.Fatalf("expected no error, but got: %s", err.Error())
+			}
+
+			fm, ok := m.(*file.FileMetadata)
+			if !ok {
+				t.Fatalf("expected file metadata, but got: %T", m)
+			}
+			if fm.Path != destination || fm.SHA == "" {
+				t.Errorf("unexpected metadata: %+v", fm)
+			}
+
+			files := readArchive(t, destination)
+			expected := map[string]string{"README.md": "readme", "policy/main.rego": "package main"}
+			if len(files) != len(expected) {
+				t.Errorf("expected %d files in the archive, but got: %v", len(expected), files)
+			}
+			for name, content := range expected {
+				if files[name] != content {
+					t.Errorf("expected %s to contain %q, but got: %q", name, content, files[name])
+				}
+			}
+		})
+	}
+
+	t.Run("FileSource", func(t *testing.T) {
+		// A single file is copied to the destination rather than packed
+		destination := filepath.Join(t.TempDir(), "out.tar.gz")
+		_, err := Gather(ctx, filepath.Join(source, "README.md"), "file://"+destination)
+		if err != nil {
+			t.Fatalf("expected no error, but got: %s", err.Error())
+		}
+		content, err := os.ReadFile(destination)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != "readme" {
+			t.Errorf("expected the file to be copied, but got: %q", content)
+		}
+	})
+}
+
+func TestIsArchiveDestination(t *testing.T) {
+	testCases := map[string]bool{
+		"out.tar":      true,
+		"out.tar.gz":   true,
+		"OUT.TGZ":      true,
+		"out.zip":      true,
+		"out":          false,
+		"out.gz":       false,
+		"out.tar.gz/":  false,
+		"dir/file.txt": false,
+	}
+
+	for destination, expected := range testCases {
+		if actual := isArchiveDestination(destination); actual != expected {
+			t.Errorf("expected isArchiveDestination(%s) to return %t, but got: %t", destination, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package gather
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	gogather "github.com/enterprise-contract/go-gather"
+	"github.com/enterprise-contract/go-gather/gather/data"
+	"github.com/enterprise-contract/go-gather/gather/file"
+	"github.com/enterprise-contract/go-gather/gather/git"
+	"github.com/enterprise-contract/go-gather/gather/github"
+	httpGatherer "github.com/enterprise-contract/go-gather/gather/http"
+	"github.com/enterprise-contract/go-gather/gather/oci"
+)
+
+// ErrInvalidConfig is returned by NewFromConfig when a GatherConfig sets options that do not apply to its source.
+var ErrInvalidConfig = errors.New("invalid gather config")
+
+// GatherConfig declares a source to gather and the options of the Gatherer used to gather it,
+// so that gathers can be configured from JSON or YAML files.
+type GatherConfig struct {
+	// Source is the source URI, as passed to Gather.
+	Source string `json:"source" yaml:"source"`
+	// Ref is the branch, tag or commit of a git source, or the tag or digest of an OCI source.
+	Ref string `json:"ref,omitempty" yaml:"ref,omitempty"`
+	// Offline makes gathering a remote source fail with gogather.ErrNetworkDisabled.
+	Offline bool `json:"offline,omitempty" yaml:"offline,omitempty"`
+	// Auth holds the credentials used to gather the source.
+	Auth AuthConfig `json:"auth,omitempty" yaml:"auth,omitempty"`
+	// Limits holds the limits on the size of a file source.
+	Limits LimitsConfig `json:"limits,omitempty" yaml:"limits,omitempty"`
+}
+
+// AuthConfig holds the credentials used to gather a source.
+type AuthConfig struct {
+	// SSHAgent authenticates git sources cloned over SSH with the SSH agent.
+	SSHAgent bool `json:"sshAgent,omitempty" yaml:"sshAgent,omitempty"`
+	// Cookies are sent by name with the requests for an HTTP source.
+	Cookies map[string]string `json:"cookies,omitempty" yaml:"cookies,omitempty"`
+}
+
+// LimitsConfig holds the limits on the size of a file source, see file.FileGatherer.
+type LimitsConfig struct {
+	MaxTotalBytes int64 `json:"maxTotalBytes,omitempty" yaml:"maxTotalBytes,omitempty"`
+	MaxFileSize   int64 `json:"maxFileSize,omitempty" yaml:"maxFileSize,omitempty"`
+	MaxFiles      int   `json:"maxFiles,omitempty" yaml:"maxFiles,omitempty"`
+}
+
+// NewFromConfig returns the Gatherer for the source of cfg, with the options of cfg applied, and the source
+// to pass to its Gather method, which includes the Ref of cfg if it has one.
+func NewFromConfig(cfg GatherConfig) (Gatherer, string, error) {
+	srcProtocol, err := gogather.ClassifyURI(cfg.Source)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to classify source URI: %w", err)
+	}
+
+	invalid := func(option string) error {
+		return fmt.Errorf("%w: %s does not apply to %s sources", ErrInvalidConfig, option, srcProtocol)
+	}
+	if cfg.Auth.SSHAgent && srcProtocol != gogather.GitURI {
+		return nil, "", invalid("auth.sshAgent")
+	}
+	if len(cfg.Auth.Cookies) > 0 && srcProtocol != gogather.HTTPURI {
+		return nil, "", invalid("auth.cookies")
+	}
+	if cfg.Limits != (LimitsConfig{}) && srcProtocol != gogather.FileURI {
+		return nil, "", invalid("limits")
+	}
+
+	source := cfg.Source
+	switch srcProtocol {
+	case gogather.DataURI:
+		if cfg.Ref != "" {
+			return nil, "", invalid("ref")
+		}
+		return &data.DataGatherer{}, source, nil
+	case gogather.FileURI:
+		if cfg.Ref != "" {
+			return nil, "", invalid("ref")
+		}
+		return &file.FileGatherer{
+			MaxTotalBytes: cfg.Limits.MaxTotalBytes,
+			FileSizeLimit: cfg.Limits.MaxFileSize,
+			FilesLimit:    cfg.Limits.MaxFiles,
+		}, source, nil
+	case gogather.GitURI:
+		if cfg.Ref != "" {
+			if strings.Contains(source, "ref=") {
+				return nil, "", fmt.Errorf("%w: ref is set in both the source and the config", ErrInvalidConfig)
+			}
+			separator := "?"
+			if strings.Contains(source, "?") {
+				separator = "&"
+			}
+			source += separator + "ref=" + cfg.Ref
+		}
+		g := &git.GitGatherer{Offline: cfg.Offline}
+		if cfg.Auth.SSHAgent {
+			g.Authenticator = &git.RealSSHAuthenticator{}
+		}
+		return g, source, nil
+	case gogather.GitHubURI:
+		if cfg.Ref != "" {
+			return nil, "", invalid("ref")
+		}
+		return &github.GitHubGatherer{Offline: cfg.Offline}, source, nil
+	case gogather.HTTPURI:
+		if cfg.Ref != "" {
+			return nil, "", invalid("ref")
+		}
+		h := httpGatherer.NewHTTPGatherer()
+		h.Offline = cfg.Offline
+		names := make([]string, 0, len(cfg.Auth.Cookies))
+		for name := range cfg.Auth.Cookies {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			h.Cookies = append(h.Cookies, &http.Cookie{Name: name, Value: cfg.Auth.Cookies[name]})
+		}
+		return h, source, nil
+	case gogather.OCIURI:
+		if cfg.Ref != "" {
+			if strings.Contains(source, "@") || strings.Contains(source[strings.LastIndex(source, "/")+1:], ":") {
+				return nil, "", fmt.Errorf("%w: ref is set in both the source and the config", ErrInvalidConfig)
+			}
+			separator := ":"
+			if strings.Contains(cfg.Ref, ":") {
+				separator = "@"
+			}
+			source += separator + cfg.Ref
+		}
+		return &oci.OCIGatherer{Offline: cfg.Offline}, source, nil
+	}
+	return nil, "", fmt.Errorf("%w: %s", ErrNoGatherer, srcProtocol)
+}
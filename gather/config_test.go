@@ -0,0 +1,125 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package gather
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/enterprise-contract/go-gather/gather/file"
+	"github.com/enterprise-contract/go-gather/gather/git"
+	"github.com/enterprise-contract/go-gather/gather/http"
+	"github.com/enterprise-contract/go-gather/gather/oci"
+)
+
+func TestNewFromConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		check  func(t *testing.T, g Gatherer)
+		source string
+	}{
+		{
+			name:   "file",
+			config: `{"source": "file:///tmp/policy.tar", "limits": {"maxTotalBytes": 100, "maxFileSize": 10, "maxFiles": 5}}`,
+			source: "file:///tmp/policy.tar",
+			check: func(t *testing.T, g Gatherer) {
+				expected := &file.FileGatherer{MaxTotalBytes: 100, FileSizeLimit: 10, FilesLimit: 5}
+				if !reflect.DeepEqual(g, expected) {
+					t.Errorf("expected %#v, but got %#v", expected, g)
+				}
+			},
+		},
+		{
+			name:   "git",
+			config: `{"source": "git::https://example.com/org/repo.git//policy", "ref": "v1.0.0", "offline": true, "auth": {"sshAgent": true}}`,
+			source: "git::https://example.com/org/repo.git//policy?ref=v1.0.0",
+			check: func(t *testing.T, g Gatherer) {
+				expected := &git.GitGatherer{Offline: true, Authenticator: &git.RealSSHAuthenticator{}}
+				if !reflect.DeepEqual(g, expected) {
+					t.Errorf("expected %#v, but got %#v", expected, g)
+				}
+			},
+		},
+		{
+			name:   "http",
+			config: `{"source": "https://example.com/policy.tar.gz", "auth": {"cookies": {"session": "abc", "csrf": "def"}}}`,
+			source: "https://example.com/policy.tar.gz",
+			check: func(t *testing.T, g Gatherer) {
+				h, ok := g.(*http.HTTPGatherer)
+				if !ok {
+					t.Fatalf("expected an HTTPGatherer, but got %T", g)
+				}
+				if h.Client.Timeout != http.NewHTTPGatherer().Client.Timeout {
+					t.Errorf("expected the default client timeout, but got %v", h.Client.Timeout)
+				}
+				if len(h.Cookies) != 2 || h.Cookies[0].Name != "csrf" || h.Cookies[1].Value != "abc" {
+					t.Errorf("expected the csrf and session cookies, but got %v", h.Cookies)
+				}
+			},
+		},
+		{
+			name:   "oci",
+			config: `{"source": "oci://quay.io/org/policy", "ref": "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"}`,
+			source: "oci://quay.io/org/policy@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+			check: func(t *testing.T, g Gatherer) {
+				if !reflect.DeepEqual(g, &oci.OCIGatherer{}) {
+					t.Errorf("expected an OCIGatherer, but got %#v", g)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg GatherConfig
+			if err := json.Unmarshal([]byte(tt.config), &cfg); err != nil {
+				t.Fatal(err)
+			}
+
+			g, source, err := NewFromConfig(cfg)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if source != tt.source {
+				t.Errorf("expected source %q, but got %q", tt.source, source)
+			}
+			tt.check(t, g)
+		})
+	}
+}
+
+func TestNewFromConfig_Invalid(t *testing.T) {
+	configs := map[string]GatherConfig{
+		"limits for git":     {Source: "git::https://example.com/org/repo.git", Limits: LimitsConfig{MaxFiles: 1}},
+		"cookies for oci":    {Source: "quay.io/org/policy", Auth: AuthConfig{Cookies: map[string]string{"a": "b"}}},
+		"ssh agent for http": {Source: "https://example.com/policy.tar", Auth: AuthConfig{SSHAgent: true}},
+		"ref for http":       {Source: "https://example.com/policy.tar", Ref: "v1"},
+		"ref in git source":  {Source: "git::https://example.com/org/repo.git?ref=main", Ref: "v1"},
+		"tag in oci source":  {Source: "quay.io/org/policy:latest", Ref: "v1"},
+	}
+
+	for name, cfg := range configs {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := NewFromConfig(cfg); !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("expected error to wrap ErrInvalidConfig, but got: %v", err)
+			}
+		})
+	}
+}
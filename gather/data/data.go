@@ -0,0 +1,115 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package data provides functionality for gathering the content inlined in RFC 2397 "data:" URIs.
+// It includes a DataGatherer struct that implements the Gatherer interface
+// and decodes the content of a data URI into a destination file.
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	gogather "github.com/enterprise-contract/go-gather"
+	"github.com/enterprise-contract/go-gather/metadata"
+	"github.com/enterprise-contract/go-gather/metadata/file"
+)
+
+// ErrInvalidDataURI is returned when the source is not a valid data URI.
+var ErrInvalidDataURI = errors.New("invalid data URI")
+
+// DataGatherer is a struct that implements the Gatherer interface
+// and provides methods for gathering the content of data URIs.
+type DataGatherer struct{}
+
+// Describe returns information about the sources handled by the DataGatherer and how it writes them.
+func (d *DataGatherer) Describe() gogather.GathererInfo {
+	return gogather.GathererInfo{
+		Name:        "data",
+		Prefixes:    []string{"data:"},
+		Destination: "the decoded content is written to the destination file",
+	}
+}
+
+// Gather decodes the content of the data URI source, e.g. "data:text/plain;base64,aGVsbG8=", and writes it to the destination file.
+func (d *DataGatherer) Gather(ctx context.Context, source, destination string) (metadata.Metadata, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	content, err := decode(source)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := gogather.ValidateDestination(destination); err != nil {
+		return nil, err
+	}
+
+	path := gogather.ExpandTilde(strings.TrimPrefix(destination, "file://"))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write file: %w", err)
+	}
+
+	sum := sha256.Sum256(content)
+	return &file.FileMetadata{
+		Size:      int64(len(content)),
+		Path:      path,
+		Timestamp: gogather.NowFunc(),
+		SHA:       hex.EncodeToString(sum[:]),
+	}, nil
+}
+
+// decode returns the content of the data URI source, which is base64 encoded if its media type
+// ends with ";base64", and percent encoded otherwise.
+func decode(source string) ([]byte, error) {
+	rest, ok := strings.CutPrefix(source, "data:")
+	if !ok {
+		return nil, fmt.Errorf("%w: %s does not start with \"data:\"", ErrInvalidDataURI, source)
+	}
+
+	mediaType, data, ok := strings.Cut(rest, ",")
+	if !ok {
+		return nil, fmt.Errorf("%w: missing \",\" before the data", ErrInvalidDataURI)
+	}
+
+	if strings.HasSuffix(mediaType, ";base64") {
+		content, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidDataURI, err)
+		}
+		return content, nil
+	}
+
+	content, err := url.PathUnescape(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidDataURI, err)
+	}
+	return []byte(content), nil
+}
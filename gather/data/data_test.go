@@ -0,0 +1,83 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package data
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gogather "github.com/enterprise-contract/go-gather"
+	"github.com/enterprise-contract/go-gather/metadata/file"
+)
+
+// TestDataGatherer_Gather tests decoding data URIs to a destination file.
+func TestDataGatherer_Gather(t *testing.T) {
+	testCases := []struct {
+		name     string
+		source   string
+		expected string
+		err      error
+	}{
+		{name: "base64", source: "data:text/plain;base64,aGVsbG8gd29ybGQ=", expected: "hello world"},
+		{name: "plain", source: "data:,hello%20world", expected: "hello world"},
+		{name: "plain with media type", source: "data:text/plain;charset=utf-8,package%20main", expected: "package main"},
+		{name: "missing comma", source: "data:text/plain", err: ErrInvalidDataURI},
+		{name: "invalid base64", source: "data:;base64,!!!", err: ErrInvalidDataURI},
+		{name: "invalid escape", source: "data:,100%", err: ErrInvalidDataURI},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			destination := filepath.Join(t.TempDir(), "sub", "file.txt")
+			m, err := (&DataGatherer{}).Gather(context.Background(), tc.source, destination)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("Expected error %v, but got %v", tc.err, err)
+			}
+			if tc.err != nil {
+				return
+			}
+
+			content, err := os.ReadFile(destination)
+			if err != nil || string(content) != tc.expected {
+				t.Errorf("Expected %q to be written, but got %q, %v", tc.expected, content, err)
+			}
+			if size := m.(*file.FileMetadata).Size; size != int64(len(tc.expected)) {
+				t.Errorf("Expected size %d, but got %d", len(tc.expected), size)
+			}
+		})
+	}
+}
+
+// TestDataGatherer_Gather_Timestamp tests that the metadata is stamped with the time of gogather.NowFunc.
+func TestDataGatherer_Gather_Timestamp(t *testing.T) {
+	fixed := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	original := gogather.NowFunc
+	gogather.NowFunc = func() time.Time { return fixed }
+	t.Cleanup(func() { gogather.NowFunc = original })
+
+	m, err := (&DataGatherer{}).Gather(context.Background(), "data:,hello", filepath.Join(t.TempDir(), "file.txt"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ts := m.(*file.FileMetadata).Timestamp; !ts.Equal(fixed) {
+		t.Errorf("Expected timestamp %v, but got %v", fixed, ts)
+	}
+}
@@ -0,0 +1,9 @@
+module github.com/enterprise-contract/go-gather/gather/data
+
+go 1.21.9
+
+require (
+	github.com/enterprise-contract/go-gather v0.0.2
+	github.com/enterprise-contract/go-gather/metadata v0.0.2
+	github.com/enterprise-contract/go-gather/metadata/file v0.0.1
+)
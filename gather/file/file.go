@@ -23,80 +23,279 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 	"sync"
-	"time"
 
+	gogather "github.com/enterprise-contract/go-gather"
 	"github.com/enterprise-contract/go-gather/expander"
 	"github.com/enterprise-contract/go-gather/metadata"
 	"github.com/enterprise-contract/go-gather/metadata/file"
 	"github.com/enterprise-contract/go-gather/saver"
 )
 
+var (
+	// ErrSourceNotFound is returned when the source file or directory does not exist.
+	ErrSourceNotFound = errors.New("source does not exist")
+	// ErrSourceTooLarge is returned when the source is larger than the configured MaxTotalBytes.
+	ErrSourceTooLarge = errors.New("source exceeds the maximum total size")
+	// ErrRemoteHost is returned when a file URI names a host other than localhost.
+	ErrRemoteHost = errors.New("file URI refers to a remote host")
+	// ErrSizeMismatch is returned when VerifySize finds less content in the destination than was extracted.
+	ErrSizeMismatch = errors.New("extracted size mismatch")
+	// ErrDestinationKindMismatch is returned when the source is a directory and the destination an existing file, or vice versa.
+	ErrDestinationKindMismatch = errors.New("destination kind does not match source")
+)
+
+// directorySize returns the total size and number of the regular files in a directory.
+var directorySize = gogather.GetDirectorySizeFS
+
+// windowsDrivePath matches a path starting with a Windows drive letter, e.g. "C:\data" or "C:/data".
+var windowsDrivePath = regexp.MustCompile(`^[A-Za-z]:([\\/]|$)`)
+
+// extractedMarker is the file in which SkipIfPresent records the SHA256 hash of the archive expanded into a destination.
+const extractedMarker = ".go-gather-extracted"
+
+// vcsDirs are the names of the version control metadata directories SkipVCS leaves out.
+var vcsDirs = []string{".git", ".hg", ".svn", ".bzr"}
+
 // FileGatherer is a struct that implements the Gatherer interface
 // and provides methods for gathering files and directories.
-type FileGatherer struct{}
+type FileGatherer struct {
+	// MaxTotalBytes is the maximum total size of the source, in bytes. A source
+	// exceeding it is refused before anything is copied. Zero means no limit.
+	MaxTotalBytes int64
+	// FileSizeLimit is the maximum total size in bytes of the files extracted from a tar archive source,
+	// and FilesLimit the maximum number of entries. If FileSizeLimit is zero, MaxTotalBytes is used.
+	// Zero means the limits of expander.TarExpander apply.
+	FileSizeLimit int64
+	FilesLimit    int
+	// RecursiveExpand expands any archives found within a gathered tar archive, see expander.TarExpander.
+	RecursiveExpand bool
+	// Extract determines whether a tar archive source is expanded into the destination or copied as is.
+	// If nil, it is expanded when the destination is a directory, see destinationIsDir.
+	Extract *bool
+	// SkipIfPresent skips expanding a tar archive into a destination it has already been expanded into,
+	// as recorded by the hash of the archive in a marker file written to the destination.
+	SkipIfPresent bool
+	// VerifySize makes Gather check that the destination holds at least as many bytes as were extracted
+	// from a tar archive, failing with ErrSizeMismatch if the extraction was cut short.
+	VerifySize bool
+	// PreserveXattr copies the extended attributes of each copied file, such as SELinux labels and file
+	// capabilities, to the destination. It is only supported on Linux, and is ignored on other platforms.
+	PreserveXattr bool
+	// DirMode is the mode of the directories created when a directory is copied, before the umask is applied.
+	// If zero, gogather.DefaultDirMode is used.
+	DirMode os.FileMode
+	// TrailingSlash gives a trailing separator on a directory source the meaning it has for rsync: a source
+	// ending with a separator has its contents copied into the destination, and one without is copied into
+	// a child of the destination named after it. If false, the contents are always copied into the destination.
+	TrailingSlash bool
+	// Exclude lists glob patterns, as understood by filepath.Match, of the files and directories left out when a
+	// directory is copied. A pattern is matched against both the path relative to the source and the base name.
+	Exclude []string
+	// SkipVCS leaves the metadata directories of version control systems, such as .git, out when a directory is copied.
+	SkipVCS bool
+	// FS is the filesystem files and directories are copied into in place of the local filesystem, e.g. a
+	// saver.MemoryFS to gather into memory. Tar archives are copied as is rather than expanded, and PreserveXattr
+	// does not apply.
+	FS saver.FS
+}
+
+// dirMode returns the mode directories are created with.
+func (f *FileGatherer) dirMode() os.FileMode {
+	if f.DirMode == 0 {
+		return gogather.DefaultDirMode
+	}
+	return f.DirMode
+}
+
+// Describe returns information about the sources handled by the FileGatherer and how it writes them.
+func (f *FileGatherer) Describe() gogather.GathererInfo {
+	return gogather.GathererInfo{
+		Name:        "file",
+		Prefixes:    []string{"file::", "file://", "/", "./", "../", "~/"},
+		Extracts:    true,
+		Destination: "a file is copied to the destination path, a directory is copied into the destination directory, and a tar archive is expanded into the destination directory",
+	}
+}
 
 // Gather copies a file or directory from the source path to the destination path.
 // It returns the metadata of the gathered file or directory and any error encountered.
 func (f *FileGatherer) Gather(ctx context.Context, source, destination string) (metadata.Metadata, error) {
-	// Parse the source URI
-	src, err := url.Parse(source)
+	if f.FS == nil {
+		if err := gogather.ValidateDestination(destination); err != nil {
+			return nil, err
+		}
+	}
 
+	// Parse the source URI
+	srcPath, err := localPath(source)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse source URI: %w", err)
 	}
 
 	// Determine if we have a file or directory
-	sourceKind, err := os.Stat(src.Path)
+	sourceKind, err := os.Stat(srcPath)
 	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %w", ErrSourceNotFound, err)
+		}
 		return nil, fmt.Errorf("failed to determine source kind: %w", err)
 	}
 
-	// Determine if we have a tar file as the src. If so, we need to untar it.
-	if strings.HasSuffix(src.Path, ".tar") {
-		dst, err := url.Parse(destination)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse destination URI: %w", err)
+	// Determine if we have a tar archive as the src. If so, we need to untar it, unless it is to be copied as is.
+	dstPath, err := localPath(destination)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse destination URI: %w", err)
+	}
+
+	if expander.IsArchive(srcPath) && f.FS == nil && f.extract(dstPath) {
+		fileSizeLimit := f.FileSizeLimit
+		if fileSizeLimit == 0 {
+			fileSizeLimit = f.MaxTotalBytes
 		}
 
 		t := &expander.TarExpander{
-			FilesLimit:    0,
-			FileSizeLimit: 0,
+			FilesLimit:      f.FilesLimit,
+			FileSizeLimit:   fileSizeLimit,
+			RecursiveExpand: f.RecursiveExpand,
 		}
 
-		err = t.Expand(dst.Path, src.Path, true, 0755)
-		if err != nil {
-			return nil, fmt.Errorf("failed to expand tar file: %w", err)
+		var sha string
+		present := false
+		if f.SkipIfPresent {
+			if sha, err = getFileSha(srcPath); err != nil {
+				return nil, err
+			}
+			marker, err := os.ReadFile(filepath.Join(dstPath, extractedMarker))
+			present = err == nil && string(marker) == sha
+		}
+
+		var result expander.ExpandResult
+		if !present {
+			_, statErr := os.Stat(dstPath)
+			result, err = t.ExpandWithResult(ctx, dstPath, srcPath, true, 0755)
+			if err != nil {
+				// Don't leave a partially expanded archive behind in a destination created for it
+				if os.IsNotExist(statErr) {
+					_ = os.RemoveAll(dstPath)
+				}
+				return nil, fmt.Errorf("failed to expand tar file: %w", err)
+			}
+
+			if f.SkipIfPresent {
+				if err := os.WriteFile(filepath.Join(dstPath, extractedMarker), []byte(sha), 0600); err != nil {
+					return nil, fmt.Errorf("failed to record expanded tar file: %w", err)
+				}
+			}
 		}
 
-		info, err := os.Stat(destination)
+		// The marker is not part of the archive, so it is left out of the description of what was expanded
+		expanded := withoutMarker{os.DirFS(dstPath)}
+		size, files, err := directorySize(expanded)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get file info: %w", err)
+			return nil, fmt.Errorf("failed to get directory size: %w", err)
+		}
+		digest, err := gogather.DirectoryDigestFS(expanded)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get directory digest: %w", err)
+		}
+
+		if f.VerifySize && size < result.Size {
+			return nil, fmt.Errorf("%w: %d bytes were extracted to %s, but it holds %d", ErrSizeMismatch, result.Size, dstPath, size)
 		}
 
-		return &file.FileMetadata{
-			Size:      info.Size(),
+		return &file.DirectoryMetadata{
+			Size:      size,
+			FileCount: files,
 			Path:      destination,
-			Timestamp: info.ModTime(),
+			Timestamp: gogather.NowFunc(),
+			SHA:       digest,
 		}, nil
 	}
 
+	if f.MaxTotalBytes > 0 {
+		size := sourceKind.Size()
+		if sourceKind.IsDir() {
+			if size, _, err = directorySize(os.DirFS(srcPath)); err != nil {
+				return nil, fmt.Errorf("failed to determine source size: %w", err)
+			}
+		}
+		if size > f.MaxTotalBytes {
+			return nil, fmt.Errorf("%w: %d bytes exceeds the %d limit", ErrSourceTooLarge, size, f.MaxTotalBytes)
+		}
+	}
+
+	if f.TrailingSlash && sourceKind.IsDir() && !hasTrailingSeparator(source) {
+		name, err := dirName(srcPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine source name: %w", err)
+		}
+		destination = strings.TrimRight(destination, "/"+string(filepath.Separator)) + "/" + name
+		dstPath = filepath.Join(dstPath, name)
+	}
+
+	if info, err := f.stat(destination); err == nil && info.IsDir() != sourceKind.IsDir() {
+		if sourceKind.IsDir() {
+			return nil, fmt.Errorf("%w: source %s is a directory, but destination %s is a file", ErrDestinationKindMismatch, srcPath, dstPath)
+		}
+		return nil, fmt.Errorf("%w: source %s is a file, but destination %s is a directory", ErrDestinationKindMismatch, srcPath, dstPath)
+	}
+
 	// If it's a directory, call copyDirectory, otherwise call copyFile
 	if sourceKind.IsDir() {
-		return f.copyDirectory(ctx, src.Path, destination)
+		return f.copyDirectory(ctx, srcPath, destination)
 	} else {
-		return f.copyFile(ctx, src.Path, destination)
+		return f.copyFile(ctx, srcPath, destination)
 	}
 }
 
+// extract reports whether an archive source is expanded into the destination path
+func (f *FileGatherer) extract(destination string) bool {
+	if f.Extract != nil {
+		return *f.Extract
+	}
+	return destinationIsDir(destination)
+}
+
+// hasTrailingSeparator reports whether a path or URI ends with a separator
+func hasTrailingSeparator(path string) bool {
+	return strings.HasSuffix(path, "/") || strings.HasSuffix(path, string(filepath.Separator))
+}
+
+// dirName returns the name of the directory at path, resolving relative paths such as "." first
+func dirName(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Base(abs), nil
+}
+
+// destinationIsDir reports whether the destination path is, or is meant to be, a directory:
+// an existing directory, a path ending with a separator, or a path that does not exist and has no extension.
+func destinationIsDir(destination string) bool {
+	if hasTrailingSeparator(destination) {
+		return true
+	}
+	info, err := os.Stat(destination)
+	if err == nil {
+		return info.IsDir()
+	}
+	return filepath.Ext(destination) == ""
+}
+
 func (f *FileGatherer) copyFile(ctx context.Context, source, destination string) (metadata.Metadata, error) {
-	src, err := url.Parse(source)
+	srcPath, err := localPath(source)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse source URI: %w", err)
 	}
@@ -107,7 +306,7 @@ func (f *FileGatherer) copyFile(ctx context.Context, source, destination string)
 	}
 
 	// Open the source file.
-	srcFile, err := os.Open(filepath.Clean(src.Path))
+	srcFile, err := os.Open(filepath.Clean(srcPath))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open source file: %w", err)
 	}
@@ -120,24 +319,35 @@ func (f *FileGatherer) copyFile(ctx context.Context, source, destination string)
 	}
 
 	// Create the appropriate Saver to handle storing the data.
-	saver, err := saver.NewSaver(destFile.Scheme)
+	s, err := f.saver(destFile.Scheme)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create saver: %w", err)
 	}
 
 	// Save the file to the destination.
-	if err := saver.Save(ctx, srcFile, destination); err != nil {
+	if err := s.Save(ctx, srcFile, destination); err != nil {
 		return nil, fmt.Errorf("failed to save file: %w", err)
 	}
 
+	destPath, err := localPath(destination)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse destination URI: %w", err)
+	}
+
+	if f.PreserveXattr && f.FS == nil {
+		if err := copyXattrs(srcPath, destPath); err != nil {
+			return nil, fmt.Errorf("failed to copy extended attributes: %w", err)
+		}
+	}
+
 	// Get the file info
-	info, err := os.Stat(destFile.Path)
+	info, err := f.stat(destination)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get file info: %w", err)
 	}
 
 	// Calculate the SHA256 hash of the file
-	fileSha, err := getFileSha(destFile.Path)
+	fileSha, err := f.destinationSha(destination)
 	if err != nil {
 		return nil, fmt.Errorf("failed to calculate file SHA: %w", err)
 	}
@@ -156,7 +366,7 @@ func (f *FileGatherer) copyFile(ctx context.Context, source, destination string)
 // It limits the number of concurrent operations to 10 to avoid overwhelming system resources.
 // It returns the metadata of the copied directory and any error encountered.
 func (f *FileGatherer) copyDirectory(ctx context.Context, source, destination string) (metadata.Metadata, error) {
-	src, err := url.Parse(source)
+	srcPath, err := localPath(source)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse source URI: %w", err)
 	}
@@ -164,6 +374,10 @@ func (f *FileGatherer) copyDirectory(ctx context.Context, source, destination st
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse destination URI: %w", err)
 	}
+	dstPath, err := localPath(destination)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse destination URI: %w", err)
+	}
 
 	errChan := make(chan error, 100) // Increased buffer size
 	done := make(chan bool)
@@ -173,7 +387,7 @@ func (f *FileGatherer) copyDirectory(ctx context.Context, source, destination st
 
 	go func() {
 		defer close(done)
-		err = filepath.Walk(src.Path, func(path string, info os.FileInfo, err error) error {
+		err = filepath.Walk(srcPath, func(path string, info os.FileInfo, err error) error {
 			if err != nil {
 				return fmt.Errorf("failed to walk path: %w", err)
 			}
@@ -184,14 +398,20 @@ func (f *FileGatherer) copyDirectory(ctx context.Context, source, destination st
 			default:
 			}
 
-			relPath, err := filepath.Rel(src.Path, path)
+			relPath, err := filepath.Rel(srcPath, path)
 			if err != nil {
 				return fmt.Errorf("failed to get relative path: %w", err)
 			}
+			if relPath != "." && f.excluded(relPath, info.IsDir()) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
 
-			destPath := filepath.Join(dst.Path, relPath)
+			destPath := filepath.Join(dstPath, relPath)
 			if info.IsDir() {
-				if err := os.MkdirAll(destPath, 0755); err != nil {
+				if err := f.mkdirAll(destPath); err != nil {
 					return fmt.Errorf("failed to create directory: %w", err)
 				}
 			} else {
@@ -209,16 +429,22 @@ func (f *FileGatherer) copyDirectory(ctx context.Context, source, destination st
 					}
 					defer srcFile.Close()
 
-					saver, err := saver.NewSaver(dst.Scheme)
+					s, err := f.saver(dst.Scheme)
 					if err != nil {
 						errChan <- err
 						return
 					}
 
-					if err := saver.Save(ctx, srcFile, destPath); err != nil {
+					if err := s.Save(ctx, srcFile, destPath); err != nil {
 						errChan <- err
 						return
 					}
+
+					if f.PreserveXattr && f.FS == nil {
+						if err := copyXattrs(path, destPath); err != nil {
+							errChan <- fmt.Errorf("failed to copy extended attributes: %w", err)
+						}
+					}
 				}()
 			}
 			return nil
@@ -238,12 +464,136 @@ func (f *FileGatherer) copyDirectory(ctx context.Context, source, destination st
 		}
 	}
 	<-done
+
+	copied := os.DirFS(dstPath)
+	if f.FS != nil {
+		copied = saver.Sub(f.FS, dstPath)
+	}
+	size, files, err := directorySize(copied)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get directory size: %w", err)
+	}
+	digest, err := gogather.DirectoryDigestFS(copied)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get directory digest: %w", err)
+	}
+
 	return &file.DirectoryMetadata{
-		Path:      dst.Path,
-		Timestamp: time.Now(),
+		Size:      size,
+		FileCount: files,
+		Path:      dstPath,
+		Timestamp: gogather.NowFunc(),
+		SHA:       digest,
 	}, nil
 }
 
+// withoutMarker is the files of a directory an archive was expanded into, without the extractedMarker.
+type withoutMarker struct {
+	fs.FS
+}
+
+func (w withoutMarker) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := fs.ReadDir(w.FS, name)
+	if name == "." {
+		entries = slices.DeleteFunc(entries, func(e fs.DirEntry) bool { return e.Name() == extractedMarker })
+	}
+	return entries, err
+}
+
+// saver returns the FS, if set, or otherwise the Saver for the scheme of the destination
+func (f *FileGatherer) saver(scheme string) (saver.Saver, error) {
+	if f.FS != nil {
+		return f.FS, nil
+	}
+	return saver.NewSaver(scheme)
+}
+
+// mkdirAll creates the directory at path, and any parents, in the FS if it is set
+func (f *FileGatherer) mkdirAll(path string) error {
+	if f.FS != nil {
+		return f.FS.MkdirAll(path, f.dirMode())
+	}
+	return os.MkdirAll(path, f.dirMode())
+}
+
+// stat returns information about the file or directory at the destination, from the FS if it is set
+func (f *FileGatherer) stat(destination string) (fs.FileInfo, error) {
+	if f.FS == nil {
+		path, err := localPath(destination)
+		if err != nil {
+			return nil, err
+		}
+		return os.Stat(path)
+	}
+	file, err := f.FS.Open(destination)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return file.Stat()
+}
+
+// destinationSha calculates the SHA256 hash of the file copied to the destination, reading it from the FS if it is set
+func (f *FileGatherer) destinationSha(destination string) (string, error) {
+	if f.FS == nil {
+		path, err := localPath(destination)
+		if err != nil {
+			return "", err
+		}
+		return getFileSha(path)
+	}
+	file, err := f.FS.Open(destination)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+	return readSha(file)
+}
+
+// excluded reports whether the file or directory at relPath within a copied directory is left out of the copy.
+func (f *FileGatherer) excluded(relPath string, isDir bool) bool {
+	name := filepath.Base(relPath)
+	if f.SkipVCS && isDir && slices.Contains(vcsDirs, name) {
+		return true
+	}
+	for _, pattern := range f.Exclude {
+		if ok, _ := filepath.Match(pattern, relPath); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// localPath returns the local filesystem path of a file URI or path. The "file::" prefix is removed, a
+// "file://" URI may only have an empty or "localhost" authority, and a Windows drive path such as
+// "file:///C:/data" or "C:\data" is returned without a leading slash.
+func localPath(uri string) (string, error) {
+	uri = strings.TrimPrefix(uri, "file::")
+	if windowsDrivePath.MatchString(uri) {
+		return uri, nil
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "file" {
+		return u.Path, nil
+	}
+
+	if u.Host != "" && u.Host != "localhost" {
+		return "", fmt.Errorf("%w: %s", ErrRemoteHost, u.Host)
+	}
+	path := u.Path
+	if windowsDrivePath.MatchString(strings.TrimPrefix(path, "/")) {
+		path = strings.TrimPrefix(path, "/")
+	}
+	return filepath.FromSlash(path), nil
+}
+
 // getFileSha calculates the SHA256 hash of a file located at the given path.
 // It returns the hexadecimal representation of the hash and any error encountered.
 // If the file cannot be opened or an error occurs while calculating the hash, an empty string and the error are returned.
@@ -255,8 +605,13 @@ func getFileSha(path string) (string, error) {
 	}
 	defer file.Close()
 
+	return readSha(file)
+}
+
+// readSha calculates the SHA256 hash of the data read from r, returning its hexadecimal representation.
+func readSha(r io.Reader) (string, error) {
 	hasher := sha256.New()
-	if _, err := io.Copy(hasher, file); err != nil {
+	if _, err := io.Copy(hasher, r); err != nil {
 		return "", fmt.Errorf("failed to calculate file SHA: %w", err)
 	}
 
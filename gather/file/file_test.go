@@ -17,11 +17,23 @@
 package file
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	gogather "github.com/enterprise-contract/go-gather"
+	"github.com/enterprise-contract/go-gather/expander"
+	"github.com/enterprise-contract/go-gather/metadata/file"
+	"github.com/enterprise-contract/go-gather/saver"
 )
 
 func TestFileGatherer_Gather(t *testing.T) {
@@ -89,6 +101,9 @@ func TestFileGatherer_Gather_Error(t *testing.T) {
 	if err == nil {
 		t.Error("expected an error, but got nil")
 	}
+	if !errors.Is(err, ErrSourceNotFound) {
+		t.Errorf("expected error to wrap ErrSourceNotFound, but got: %v", err)
+	}
 }
 
 // TestFileGatherer_URLParseError tests the error handling of the URL parsing
@@ -341,3 +356,682 @@ func TestFileGatherer_getFileSha_OpenFileError(t *testing.T) {
 	}
 
 }
+
+func TestFileGatherer_Gather_MaxTotalBytes(t *testing.T) {
+	// Create a source directory containing 10 bytes spread over two files
+	source := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(source, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(source, "a.txt"), []byte("12345"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(source, "sub", "b.txt"), []byte("67890"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("directory exceeds limit", func(t *testing.T) {
+		destination := filepath.Join(t.TempDir(), "dst")
+		gatherer := &FileGatherer{MaxTotalBytes: 9}
+		_, err := gatherer.Gather(context.Background(), source, destination)
+		if !errors.Is(err, ErrSourceTooLarge) {
+			t.Errorf("expected error to wrap ErrSourceTooLarge, but got: %v", err)
+		}
+		if _, err := os.Stat(destination); !os.IsNotExist(err) {
+			t.Errorf("expected nothing to be copied, but got: %v", err)
+		}
+	})
+
+	t.Run("file exceeds limit", func(t *testing.T) {
+		gatherer := &FileGatherer{MaxTotalBytes: 4}
+		_, err := gatherer.Gather(context.Background(), filepath.Join(source, "a.txt"), filepath.Join(t.TempDir(), "a.txt"))
+		if !errors.Is(err, ErrSourceTooLarge) {
+			t.Errorf("expected error to wrap ErrSourceTooLarge, but got: %v", err)
+		}
+	})
+
+	t.Run("directory within limit", func(t *testing.T) {
+		destination := filepath.Join(t.TempDir(), "dst")
+		gatherer := &FileGatherer{MaxTotalBytes: 10}
+		if _, err := gatherer.Gather(context.Background(), source, fmt.Sprintf("%s%s", "file://", destination)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(destination, "sub", "b.txt")); err != nil {
+			t.Errorf("expected file to be copied: %v", err)
+		}
+	})
+}
+
+func TestFileGatherer_Gather_ExtractLimits(t *testing.T) {
+	// Create an archive containing two 5 byte files
+	source := filepath.Join(t.TempDir(), "archive.tar")
+	f, err := os.Create(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tar.NewWriter(f)
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: 5, Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte("hello")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	testCases := []struct {
+		name     string
+		gatherer *FileGatherer
+		expected error
+	}{
+		{name: "size limit exceeded", gatherer: &FileGatherer{FileSizeLimit: 9}, expected: expander.ErrSizeLimitExceeded},
+		{name: "MaxTotalBytes exceeded", gatherer: &FileGatherer{MaxTotalBytes: 9}, expected: expander.ErrSizeLimitExceeded},
+		{name: "files limit exceeded", gatherer: &FileGatherer{FilesLimit: 1}, expected: expander.ErrFilesLimitExceeded},
+		{name: "within limits", gatherer: &FileGatherer{FileSizeLimit: 10, FilesLimit: 2}, expected: nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			destination := filepath.Join(t.TempDir(), "out")
+			_, err := tc.gatherer.Gather(context.Background(), source, fmt.Sprintf("%s%s", "file://", destination))
+			if !errors.Is(err, tc.expected) {
+				t.Fatalf("expected error %v, but got: %v", tc.expected, err)
+			}
+			if tc.expected == nil {
+				return
+			}
+			if _, err := os.Stat(destination); !os.IsNotExist(err) {
+				t.Errorf("expected the partially expanded destination to be removed, but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestFileGatherer_Gather_SkipIfPresent(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "archive.tar.gz")
+	createTarGz(t, source)
+	destination := filepath.Join(t.TempDir(), "out")
+	extracted := filepath.Join(destination, "file.txt")
+
+	gatherer := &FileGatherer{SkipIfPresent: true}
+	if _, err := gatherer.Gather(context.Background(), source, fmt.Sprintf("%s%s", "file://", destination)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Backdate the extracted file, which is rewritten if the archive is expanded again
+	past := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(extracted, past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := gatherer.Gather(context.Background(), source, fmt.Sprintf("%s%s", "file://", destination)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	info, err := os.Stat(extracted)
+	if err != nil {
+		t.Fatalf("expected the first gather's output to be intact: %v", err)
+	}
+	if !info.ModTime().Equal(past) {
+		t.Error("expected the second gather to be skipped")
+	}
+
+	// A different archive is expanded over the destination
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := tw.WriteHeader(&tar.Header{Name: "file.txt", Mode: 0644, Size: 5, Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write([]byte("world")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	source = filepath.Join(t.TempDir(), "archive.tar")
+	if err := os.WriteFile(source, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gatherer.Gather(context.Background(), source, fmt.Sprintf("%s%s", "file://", destination)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if content, err := os.ReadFile(extracted); err != nil || string(content) != "world" {
+		t.Errorf("expected a different archive to be expanded, but got %q, %v", content, err)
+	}
+}
+
+func TestFileGatherer_Gather_VerifySize(t *testing.T) {
+	// The archive created by createTarGz contains a single 5 byte file
+	source := filepath.Join(t.TempDir(), "archive.tar.gz")
+	createTarGz(t, source)
+
+	t.Run("complete", func(t *testing.T) {
+		gatherer := &FileGatherer{VerifySize: true}
+		if _, err := gatherer.Gather(context.Background(), source, "file://"+filepath.Join(t.TempDir(), "out")); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("short write", func(t *testing.T) {
+		// Report the destination as holding fewer bytes than were extracted, as if a write was cut short
+		original := directorySize
+		directorySize = func(fsys fs.FS) (int64, int, error) {
+			size, files, err := original(fsys)
+			return size - 1, files, err
+		}
+		t.Cleanup(func() { directorySize = original })
+
+		gatherer := &FileGatherer{VerifySize: true}
+		_, err := gatherer.Gather(context.Background(), source, "file://"+filepath.Join(t.TempDir(), "out"))
+		if !errors.Is(err, ErrSizeMismatch) {
+			t.Errorf("expected error to wrap ErrSizeMismatch, but got: %v", err)
+		}
+
+		gatherer.VerifySize = false
+		if _, err := gatherer.Gather(context.Background(), source, "file://"+filepath.Join(t.TempDir(), "out")); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestFileGatherer_Gather_UppercaseTarExtension(t *testing.T) {
+	// Create a tar file with an uppercase extension
+	source := filepath.Join(t.TempDir(), "ARCHIVE.TAR")
+	f, err := os.Create(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tar.NewWriter(f)
+	if err := tw.WriteHeader(&tar.Header{Name: "file.txt", Mode: 0644, Size: 5, Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	destination := filepath.Join(t.TempDir(), "dst")
+	gatherer := &FileGatherer{}
+	if _, err := gatherer.Gather(context.Background(), source, destination); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Assert that the tar file was expanded rather than copied
+	if _, err := os.Stat(filepath.Join(destination, "file.txt")); err != nil {
+		t.Errorf("expected tar file to be expanded: %v", err)
+	}
+}
+
+func TestFileGatherer_Describe(t *testing.T) {
+	info := (&FileGatherer{}).Describe()
+	if info.Name != "file" {
+		t.Errorf("expected name file, but got %s", info.Name)
+	}
+	if !info.Extracts {
+		t.Error("expected the file gatherer to extract archives")
+	}
+	if len(info.Prefixes) == 0 || info.Prefixes[0] != "file::" {
+		t.Errorf("unexpected prefixes: %v", info.Prefixes)
+	}
+}
+
+// createTarGz writes a gzip compressed tar archive containing file.txt to path and returns its content.
+func createTarGz(t *testing.T, path string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	if err := tw.WriteHeader(&tar.Header{Name: "file.txt", Mode: 0644, Size: 5, Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestFileGatherer_Gather_Extract(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "archive.tar.gz")
+	content := createTarGz(t, source)
+
+	extract, noExtract := true, false
+
+	testCases := []struct {
+		name        string
+		extract     *bool
+		destination string
+		expanded    bool
+	}{
+		{name: "auto with directory destination", extract: nil, destination: "out", expanded: true},
+		{name: "auto with file destination", extract: nil, destination: "copy.tar.gz", expanded: false},
+		{name: "forced extraction", extract: &extract, destination: "out.d", expanded: true},
+		{name: "extraction disabled", extract: &noExtract, destination: "out", expanded: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			destination := filepath.Join(t.TempDir(), tc.destination)
+			gatherer := &FileGatherer{Extract: tc.extract}
+			_, err := gatherer.Gather(context.Background(), source, fmt.Sprintf("%s%s", "file://", destination))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tc.expanded {
+				if _, err := os.Stat(filepath.Join(destination, "file.txt")); err != nil {
+					t.Errorf("expected archive to be expanded: %v", err)
+				}
+				return
+			}
+
+			copied, err := os.ReadFile(destination)
+			if err != nil {
+				t.Fatalf("expected archive to be copied: %v", err)
+			}
+			if !bytes.Equal(copied, content) {
+				t.Error("expected archive to be copied verbatim")
+			}
+		})
+	}
+}
+
+func TestFileGatherer_Gather_Size(t *testing.T) {
+	source := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(source, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(source, "a.txt"), []byte("hello"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(source, "sub", "b.txt"), []byte("world!"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(t.TempDir(), "archive.tar.gz")
+	createTarGz(t, archive)
+
+	gatherer := &FileGatherer{}
+
+	// A file reports its own size
+	m, err := gatherer.Gather(context.Background(), filepath.Join(source, "a.txt"), "file://"+filepath.Join(t.TempDir(), "a.txt"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fm, ok := m.(*file.FileMetadata); !ok || fm.Size != 5 {
+		t.Errorf("expected file metadata with size 5, got %#v", m)
+	}
+
+	// A directory reports the total size of the files within it
+	m, err = gatherer.Gather(context.Background(), source, "file://"+filepath.Join(t.TempDir(), "dir"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dm, ok := m.(*file.DirectoryMetadata); !ok || dm.Size != 11 || dm.FileCount != 2 {
+		t.Errorf("expected directory metadata with size 11 and 2 files, got %#v", m)
+	}
+
+	// A directory reports the same digest as the directory it was copied from
+	if sha, err := gogather.DirectoryDigest(source); err != nil || m.(*file.DirectoryMetadata).SHA != sha {
+		t.Errorf("expected directory metadata with sha %s, got %#v (%v)", sha, m, err)
+	}
+
+	// An extracted archive reports the total size of the files extracted from it
+	m, err = gatherer.Gather(context.Background(), archive, "file://"+filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dm, ok := m.(*file.DirectoryMetadata); !ok || dm.Size != 5 || dm.FileCount != 1 {
+		t.Errorf("expected directory metadata with size 5 and 1 file, got %#v", m)
+	}
+}
+
+func TestFileGatherer_Gather_Timestamp(t *testing.T) {
+	fixed := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	original := gogather.NowFunc
+	gogather.NowFunc = func() time.Time { return fixed }
+	t.Cleanup(func() { gogather.NowFunc = original })
+
+	source := t.TempDir()
+	if err := os.WriteFile(filepath.Join(source, "a.txt"), []byte("hello"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(t.TempDir(), "archive.tar.gz")
+	createTarGz(t, archive)
+
+	gatherer := &FileGatherer{}
+	for name, src := range map[string]string{"directory": source, "extracted archive": archive} {
+		t.Run(name, func(t *testing.T) {
+			m, err := gatherer.Gather(context.Background(), src, "file://"+filepath.Join(t.TempDir(), "out"))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			dm, ok := m.(*file.DirectoryMetadata)
+			if !ok {
+				t.Fatalf("expected directory metadata, got %T", m)
+			}
+			if !dm.Timestamp.Equal(fixed) {
+				t.Errorf("expected timestamp %s, got %s", fixed, dm.Timestamp)
+			}
+		})
+	}
+}
+
+func TestLocalPath(t *testing.T) {
+	testCases := []struct {
+		uri      string
+		expected string
+		err      error
+	}{
+		{uri: "/tmp/x", expected: "/tmp/x"},
+		{uri: "file:///tmp/x", expected: "/tmp/x"},
+		{uri: "file://localhost/tmp/x", expected: "/tmp/x"},
+		{uri: "file::/tmp/x", expected: "/tmp/x"},
+		{uri: "file:///C:/data", expected: filepath.FromSlash("C:/data")},
+		{uri: "file::C:\\data", expected: "C:\\data"},
+		{uri: "C:/data", expected: "C:/data"},
+		{uri: "file://server/share", err: ErrRemoteHost},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.uri, func(t *testing.T) {
+			path, err := localPath(tc.uri)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected error %v, but got: %v", tc.err, err)
+			}
+			if path != tc.expected {
+				t.Errorf("expected path %s, but got: %s", tc.expected, path)
+			}
+		})
+	}
+}
+
+func TestFileGatherer_Gather_FileURIAuthority(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "a.txt")
+	if err := os.WriteFile(source, []byte("hello"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	destination := filepath.Join(t.TempDir(), "b.txt")
+
+	gatherer := &FileGatherer{}
+	if _, err := gatherer.Gather(context.Background(), "file://localhost"+filepath.ToSlash(source), "file://"+destination); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if content, err := os.ReadFile(destination); err != nil || string(content) != "hello" {
+		t.Errorf("expected the file to be copied, but got %q, %v", content, err)
+	}
+}
+
+func TestFileGatherer_Gather_DestinationKindMismatch(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "src")
+	if err := os.MkdirAll(source, 0755); err != nil {
+		t.Fatal(err)
+	}
+	regular := filepath.Join(dir, "file.txt")
+	if err := os.WriteFile(regular, []byte("content"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		source      string
+		destination string
+		message     string
+	}{
+		{name: "directory to file", source: source, destination: regular, message: "is a directory, but destination " + regular + " is a file"},
+		{name: "file to directory", source: regular, destination: source, message: "is a file, but destination " + source + " is a directory"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := (&FileGatherer{}).Gather(context.Background(), tt.source, "file://"+tt.destination)
+			if !errors.Is(err, ErrDestinationKindMismatch) {
+				t.Fatalf("expected error to wrap ErrDestinationKindMismatch, but got: %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("expected error to contain %q, but got: %v", tt.message, err)
+			}
+		})
+	}
+}
+
+func TestFileGatherer_Gather_DirMode(t *testing.T) {
+	source := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(source, "sub"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(source, "sub", "a.txt"), []byte("hello"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, mode := range []os.FileMode{0, 0700} {
+		expected := mode
+		if expected == 0 {
+			expected = gogather.DefaultDirMode
+		}
+
+		destination := filepath.Join(t.TempDir(), "dir")
+		if _, err := (&FileGatherer{DirMode: mode}).Gather(context.Background(), source, "file://"+destination); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, dir := range []string{destination, filepath.Join(destination, "sub")} {
+			info, err := os.Stat(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != expected {
+				t.Errorf("expected %s to have mode %v, got %v", dir, expected, info.Mode().Perm())
+			}
+		}
+	}
+}
+
+// TestFileGatherer_Gather_Reused tests that a gatherer reused for a second source reports nothing from the first.
+func TestFileGatherer_Gather_Reused(t *testing.T) {
+	source := t.TempDir()
+	if err := os.WriteFile(filepath.Join(source, "a.txt"), []byte("hello world"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(source, "b.txt"), []byte("hi"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	gatherer := &FileGatherer{}
+	if _, err := gatherer.Gather(context.Background(), filepath.Join(source, "a.txt"), "file://"+filepath.Join(t.TempDir(), "a.txt")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	destination := "file://" + filepath.Join(t.TempDir(), "b.txt")
+	m, err := gatherer.Gather(context.Background(), filepath.Join(source, "b.txt"), destination)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sha, err := getFileSha(filepath.Join(source, "b.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fm, ok := m.(*file.FileMetadata)
+	if !ok || fm.Size != 2 || fm.Path != destination || fm.SHA != sha {
+		t.Errorf("expected file metadata for b.txt only, got %#v", m)
+	}
+}
+
+// TestFileGatherer_Gather_TrailingSlash tests that with TrailingSlash a directory source is copied into a child of
+// the destination named after it, unless the source ends with a separator.
+func TestFileGatherer_Gather_TrailingSlash(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "src")
+	if err := os.MkdirAll(source, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(source, "a.txt"), []byte("hello"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		trailingSlash bool
+		source        string
+		expected      string
+	}{
+		{"named child", true, source, filepath.Join("src", "a.txt")},
+		{"contents", true, source + "/", "a.txt"},
+		{"disabled", false, source, "a.txt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			destination := t.TempDir()
+			gatherer := &FileGatherer{TrailingSlash: tt.trailingSlash}
+			m, err := gatherer.Gather(context.Background(), tt.source, "file://"+destination)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := os.Stat(filepath.Join(destination, tt.expected)); err != nil {
+				t.Errorf("expected %s in the destination: %v", tt.expected, err)
+			}
+			if dm, ok := m.(*file.DirectoryMetadata); !ok || dm.FileCount != 1 || dm.Path != filepath.Dir(filepath.Join(destination, tt.expected)) {
+				t.Errorf("unexpected metadata: %#v", m)
+			}
+		})
+	}
+}
+
+// TestFileGatherer_Gather_Exclude tests that SkipVCS and Exclude leave files and directories out of a directory copy.
+func TestFileGatherer_Gather_Exclude(t *testing.T) {
+	source := t.TempDir()
+	for _, name := range []string{".git/config", ".git/objects/pack", "sub/.hidden", "sub/b.txt", "a.txt", "a.log"} {
+		path := filepath.Join(source, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		gatherer *FileGatherer
+		expected []string
+	}{
+		{"none", &FileGatherer{}, []string{".git/config", ".git/objects/pack", "a.log", "a.txt", "sub/.hidden", "sub/b.txt"}},
+		{"skip VCS", &FileGatherer{SkipVCS: true}, []string{"a.log", "a.txt", "sub/.hidden", "sub/b.txt"}},
+		{"exclude", &FileGatherer{Exclude: []string{".*", "*.log", "sub/b.txt"}}, []string{"a.txt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			destination := t.TempDir()
+			if _, err := tt.gatherer.Gather(context.Background(), source, "file://"+destination); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var copied []string
+			err := filepath.WalkDir(destination, func(path string, d os.DirEntry, err error) error {
+				if err == nil && !d.IsDir() {
+					rel, _ := filepath.Rel(destination, path)
+					copied = append(copied, filepath.ToSlash(rel))
+				}
+				return err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(copied) != fmt.Sprint(tt.expected) {
+				t.Errorf("unexpected files copied: got %v, want %v", copied, tt.expected)
+			}
+		})
+	}
+}
+
+// TestFileGatherer_Gather_FS tests that files and directories are copied into the FS without writing to disk.
+func TestFileGatherer_Gather_FS(t *testing.T) {
+	source := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(source, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(source, "a.txt"), []byte("hello"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(source, "sub", "b.txt"), []byte("world!"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	memory := saver.NewMemoryFS()
+	gatherer := &FileGatherer{FS: memory}
+	destination := filepath.Join(t.TempDir(), "gathered")
+
+	m, err := gatherer.Gather(context.Background(), filepath.Join(source, "a.txt"), "file://"+filepath.Join(destination, "a.txt"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sha, err := getFileSha(filepath.Join(source, "a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fm, ok := m.(*file.FileMetadata); !ok || fm.Size != 5 || fm.SHA != sha {
+		t.Errorf("expected file metadata with size 5 and sha %s, got %#v", sha, m)
+	}
+
+	m, err = gatherer.Gather(context.Background(), source, "file://"+filepath.Join(destination, "dir"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	digest, err := gogather.DirectoryDigest(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dm, ok := m.(*file.DirectoryMetadata); !ok || dm.Size != 11 || dm.FileCount != 2 || dm.SHA != digest {
+		t.Errorf("expected directory metadata with size 11, 2 files and sha %s, got %#v", digest, m)
+	}
+
+	for name, expected := range map[string]string{"a.txt": "hello", "dir/a.txt": "hello", "dir/sub/b.txt": "world!"} {
+		content, err := fs.ReadFile(saver.Sub(memory, destination), name)
+		if err != nil || string(content) != expected {
+			t.Errorf("unexpected content of %s: got %q (%v), want %q", name, content, err, expected)
+		}
+	}
+	if _, err := os.Stat(destination); !os.IsNotExist(err) {
+		t.Errorf("expected nothing to be written to disk, but got: %v", err)
+	}
+}
+
+// TestFileGatherer_Gather_SkipIfPresentMetadata tests that the marker written by SkipIfPresent is not described in the metadata.
+func TestFileGatherer_Gather_SkipIfPresentMetadata(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "archive.tar.gz")
+	createTarGz(t, source)
+
+	gather := func(skipIfPresent bool) *file.DirectoryMetadata {
+		gatherer := &FileGatherer{SkipIfPresent: skipIfPresent}
+		m, err := gatherer.Gather(context.Background(), source, "file://"+filepath.Join(t.TempDir(), "out"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		dm, ok := m.(*file.DirectoryMetadata)
+		if !ok {
+			t.Fatalf("expected directory metadata, got %#v", m)
+		}
+		return dm
+	}
+
+	without, with := gather(false), gather(true)
+	if with.Size != without.Size || with.FileCount != without.FileCount || with.SHA != without.SHA {
+		t.Errorf("expected the same metadata with SkipIfPresent, got %#v and %#v", with, without)
+	}
+	if with.Size != 5 || with.FileCount != 1 {
+		t.Errorf("expected metadata for the 1 file of 5 bytes in the archive, got %#v", with)
+	}
+}
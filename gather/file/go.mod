@@ -3,6 +3,7 @@ module github.com/enterprise-contract/go-gather/gather/file
 go 1.21.9
 
 require (
+	github.com/enterprise-contract/go-gather v0.0.1
 	github.com/enterprise-contract/go-gather/expander v0.0.1
 	github.com/enterprise-contract/go-gather/metadata v0.0.1
 	github.com/enterprise-contract/go-gather/metadata/file v0.0.1
@@ -0,0 +1,60 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux
+
+package file
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestFileGatherer_Gather_PreserveXattr(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "source.txt")
+	if err := os.WriteFile(source, []byte("content"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Setxattr(source, "user.test", []byte("value"), 0); err != nil {
+		if errors.Is(err, syscall.ENOTSUP) || errors.Is(err, syscall.EPERM) {
+			t.Skipf("extended attributes are not supported: %v", err)
+		}
+		t.Fatal(err)
+	}
+
+	for name, preserve := range map[string]bool{"preserved": true, "not preserved": false} {
+		t.Run(name, func(t *testing.T) {
+			destination := filepath.Join(dir, name+".txt")
+			gatherer := &FileGatherer{PreserveXattr: preserve}
+			if _, err := gatherer.Gather(context.Background(), source, "file://"+destination); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			value, err := getXattr(destination, "user.test")
+			if preserve && (err != nil || string(value) != "value") {
+				t.Errorf("expected the extended attribute to be copied, but got %q, %v", value, err)
+			}
+			if !preserve && err == nil {
+				t.Errorf("expected the extended attribute not to be copied, but got %q", value)
+			}
+		})
+	}
+}
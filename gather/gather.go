@@ -22,14 +22,39 @@ package gather
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 
 	gogather "github.com/enterprise-contract/go-gather"
+	"github.com/enterprise-contract/go-gather/expander"
+	"github.com/enterprise-contract/go-gather/gather/data"
 	"github.com/enterprise-contract/go-gather/gather/file"
 	"github.com/enterprise-contract/go-gather/gather/git"
+	"github.com/enterprise-contract/go-gather/gather/github"
 	"github.com/enterprise-contract/go-gather/gather/http"
 	"github.com/enterprise-contract/go-gather/gather/oci"
 	"github.com/enterprise-contract/go-gather/metadata"
+	fileMetadata "github.com/enterprise-contract/go-gather/metadata/file"
+)
+
+var (
+	// ErrNoGatherer is returned when no Gatherer is registered for the protocol of the source URI.
+	ErrNoGatherer = errors.New("unsupported source protocol")
+	// ErrHeadNotSupported is returned by Head when the Gatherer for the source cannot resolve its metadata.
+	ErrHeadNotSupported = errors.New("gatherer does not support getting metadata without gathering")
+	// ErrUnsupportedFormat is returned when GatherReader is given a format it cannot write.
+	ErrUnsupportedFormat = errors.New("unsupported format")
+	// ErrMissingGatherer is returned by SelfCheck when no Gatherer is registered for a required protocol.
+	ErrMissingGatherer = errors.New("missing gatherer")
+	// ErrMissingExpander is returned by SelfCheck when no Expander is registered for a required archive format.
+	ErrMissingExpander = errors.New("missing expander")
 )
 
 // Gatherer is an interface that defines the behavior of a gatherer.
@@ -37,15 +62,35 @@ type Gatherer interface {
 	Gather(ctx context.Context, source, destination string) (metadata metadata.Metadata, err error)
 }
 
+// Describer is implemented by gatherers that can describe the sources they handle and how they write them.
+type Describer interface {
+	Describe() gogather.GathererInfo
+}
+
+// Header is implemented by gatherers that can resolve the metadata of a source, such as its digest or size,
+// without gathering its content.
+type Header interface {
+	Head(ctx context.Context, source string) (metadata.Metadata, error)
+}
+
+// Validator is implemented by gatherers that can check a source is well-formed without accessing it.
+type Validator interface {
+	Validate(source string) error
+}
+
 // protocolHandlers maps URL schemes to their corresponding Gatherer implementations.
 var protocolHandlers = map[string]Gatherer{
-	"FileURI": &file.FileGatherer{},
-	"GitURI":  &git.GitGatherer{},
-	"HTTPURI": &http.HTTPGatherer{},
-	"OCIURI":  &oci.OCIGatherer{},
+	"DataURI":   &data.DataGatherer{},
+	"FileURI":   &file.FileGatherer{},
+	"GitURI":    &git.GitGatherer{},
+	"GitHubURI": &github.GitHubGatherer{},
+	"HTTPURI":   &http.HTTPGatherer{},
+	"OCIURI":    &oci.OCIGatherer{},
 }
 
 // Gather determines the protocol from the source URI and uses the appropriate Gatherer to perform the operation.
+// If the source is a directory, a git repository or an OCI artifact, and the destination ends with ".tar",
+// ".tar.gz", ".tgz" or ".zip", the gathered content is packed into an archive at the destination.
 // It returns the gathered metadata and an error, if any.
 func Gather(ctx context.Context, source, destination string) (metadata.Metadata, error) {
 	srcProtocol, err := gogather.ClassifyURI(source)
@@ -53,8 +98,184 @@ func Gather(ctx context.Context, source, destination string) (metadata.Metadata,
 		return nil, fmt.Errorf("failed to classify source URI: %w", err)
 	}
 
-	if gatherer, ok := protocolHandlers[srcProtocol.String()]; ok {
-		return gatherer.Gather(ctx, source, destination)
+	return GatherWith(ctx, srcProtocol, source, destination)
+}
+
+// GatherWith is like Gather, but uses the Gatherer for srcProtocol rather than the one for the protocol the
+// source URI is classified as, for sources that ClassifyURI does not classify as the caller intends.
+func GatherWith(ctx context.Context, srcProtocol gogather.URIType, source, destination string) (metadata.Metadata, error) {
+	gatherer, ok := protocolHandlers[srcProtocol.String()]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoGatherer, srcProtocol)
+	}
+
+	if isArchiveDestination(destination) && gathersDirectory(srcProtocol, source) {
+		return gatherToArchive(ctx, gatherer, srcProtocol, source, destination)
+	}
+	return gatherer.Gather(ctx, source, destination)
+}
+
+// Head determines the protocol from the source URI and uses the appropriate Gatherer to resolve the metadata
+// of the source without gathering its content. It returns ErrHeadNotSupported if the Gatherer does not implement Header.
+func Head(ctx context.Context, source string) (metadata.Metadata, error) {
+	srcProtocol, err := gogather.ClassifyURI(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to classify source URI: %w", err)
+	}
+
+	gatherer, ok := protocolHandlers[srcProtocol.String()]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoGatherer, srcProtocol)
+	}
+
+	header, ok := gatherer.(Header)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrHeadNotSupported, srcProtocol)
+	}
+	return header.Head(ctx, source)
+}
+
+// Validate determines the protocol from the source URI and checks that the source is well-formed for the
+// appropriate Gatherer, without accessing the network. A source for a Gatherer that does not implement
+// Validator is only checked to have a protocol that can be gathered.
+func Validate(source string) error {
+	srcProtocol, err := gogather.ClassifyURI(source)
+	if err != nil {
+		return fmt.Errorf("failed to classify source URI: %w", err)
+	}
+
+	gatherer, ok := protocolHandlers[srcProtocol.String()]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNoGatherer, srcProtocol)
+	}
+
+	if validator, ok := gatherer.(Validator); ok {
+		return validator.Validate(source)
+	}
+	return nil
+}
+
+// GatherReader writes the content read from r to the destination, for sources that have already been fetched.
+// The format determines how the content is written: "tar", "tar.gz" and "tgz" archives are expanded into
+// the destination directory, and "" or "file" content is written to the destination file as is.
+// It returns the metadata of the written file or directory and an error, if any.
+func GatherReader(ctx context.Context, r io.Reader, format, destination string) (metadata.Metadata, error) {
+	switch format {
+	case "tar", "tar.gz", "tgz":
+		t := &expander.TarExpander{}
+		if err := t.ExpandReader(ctx, r, destination, 0755); err != nil {
+			return nil, fmt.Errorf("failed to expand %s stream: %w", format, err)
+		}
+
+		size, files, err := gogather.GetDirectorySize(destination)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get directory size: %w", err)
+		}
+		digest, err := gogather.DirectoryDigest(destination)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get directory digest: %w", err)
+		}
+
+		return &fileMetadata.DirectoryMetadata{
+			Size:      size,
+			FileCount: files,
+			Path:      destination,
+			Timestamp: gogather.NowFunc(),
+			SHA:       digest,
+		}, nil
+	case "", "file":
+		return writeReader(ctx, r, destination)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+}
+
+// writeReader writes the content read from r to the destination file, calculating its SHA256 hash as it goes
+func writeReader(ctx context.Context, r io.Reader, destination string) (metadata.Metadata, error) {
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("error writing file: %w", ctx.Err())
+	default:
+	}
+
+	if err := os.MkdirAll(filepath.Dir(destination), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	f, err := os.Create(destination)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file: %w", err)
+	}
+	defer f.Close()
+
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(f, hasher), r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return &fileMetadata.FileMetadata{
+		Size:      size,
+		Path:      destination,
+		Timestamp: gogather.NowFunc(),
+		SHA:       hex.EncodeToString(hasher.Sum(nil)),
+	}, nil
+}
+
+// Describe returns information about each of the built-in gatherers that implements Describer,
+// keyed by the URI type it handles.
+func Describe() map[string]gogather.GathererInfo {
+	info := make(map[string]gogather.GathererInfo, len(protocolHandlers))
+	for uriType, gatherer := range protocolHandlers {
+		if d, ok := gatherer.(Describer); ok {
+			info[uriType] = d.Describe()
+		}
+	}
+	return info
+}
+
+// requiredProtocols are the protocols SelfCheck requires a Gatherer to be registered for.
+var requiredProtocols = []gogather.URIType{gogather.FileURI, gogather.GitURI, gogather.HTTPURI, gogather.OCIURI}
+
+// requiredExpanders are the archive formats SelfCheck requires an Expander to be registered for. The tar
+// expander also expands gzip compressed tar archives.
+var requiredExpanders = []string{"tar"}
+
+// baseExpanders returns the registered expanders, and is replaced in tests.
+var baseExpanders = expander.BaseExpanders
+
+// SelfCheck verifies that a Gatherer is registered for each of the file, git, HTTP and OCI protocols, and
+// an Expander for each archive format, returning an error that lists the protocols and formats without one.
+func SelfCheck() error {
+	var missing []string
+	for _, protocol := range requiredProtocols {
+		if _, ok := protocolHandlers[protocol.String()]; !ok {
+			missing = append(missing, protocol.String())
+		}
+	}
+	var errs []error
+	if len(missing) > 0 {
+		errs = append(errs, fmt.Errorf("%w: %s", ErrMissingGatherer, strings.Join(missing, ", ")))
+	}
+
+	missing = nil
+	expanders := baseExpanders(0, 0)
+	for _, format := range requiredExpanders {
+		if _, ok := expanders[format]; !ok {
+			missing = append(missing, format)
+		}
+	}
+	if len(missing) > 0 {
+		errs = append(errs, fmt.Errorf("%w: %s", ErrMissingExpander, strings.Join(missing, ", ")))
+	}
+	return errors.Join(errs...)
+}
+
+// RegisteredSchemes returns the sorted source prefixes, e.g. "git::" or "https://", advertised by the built-in gatherers.
+func RegisteredSchemes() []string {
+	var schemes []string
+	for _, info := range Describe() {
+		schemes = append(schemes, info.Prefixes...)
 	}
-	return nil, fmt.Errorf("unsupported source protocol: %s", srcProtocol)
+	sort.Strings(schemes)
+	return schemes
 }
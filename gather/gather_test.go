@@ -17,14 +17,25 @@
 package gather
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
 	"context"
+	"errors"
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"testing"
+	"time"
 
 	gogather "github.com/enterprise-contract/go-gather"
+	"github.com/enterprise-contract/go-gather/expander"
+	gitGather "github.com/enterprise-contract/go-gather/gather/git"
+	httpGather "github.com/enterprise-contract/go-gather/gather/http"
+	ociGather "github.com/enterprise-contract/go-gather/gather/oci"
 	"github.com/enterprise-contract/go-gather/metadata"
+	"github.com/enterprise-contract/go-gather/metadata/file"
 	"github.com/enterprise-contract/go-gather/metadata/git"
 )
 
@@ -43,6 +54,9 @@ func TestGather(t *testing.T) {
 		if err.Error() != expectedErrorMessage {
 			t.Errorf("expected error message: %s, but got: %s", expectedErrorMessage, err.Error())
 		}
+		if !errors.Is(err, ErrNoGatherer) {
+			t.Errorf("expected error to wrap ErrNoGatherer, but got: %s", err.Error())
+		}
 		t.Cleanup(func() {
 			os.RemoveAll(destination)
 		})
@@ -149,3 +163,268 @@ func TestExpandTilde(t *testing.T) {
 		}
 	})
 }
+
+func TestGatherReader(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("TarGz", func(t *testing.T) {
+		// Write a gzip compressed tar archive to a buffer
+		var buf bytes.Buffer
+		gw := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gw)
+		if err := tw.WriteHeader(&tar.Header{Name: "dir/file.txt", Mode: 0644, Size: 11, Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte("hello world")); err != nil {
+			t.Fatal(err)
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := gw.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		destination := filepath.Join(t.TempDir(), "out")
+		m, err := GatherReader(ctx, &buf, "tar.gz", destination)
+		if err != nil {
+			t.Fatalf("expected no error, but got: %s", err.Error())
+		}
+		if _, ok := m.(*file.DirectoryMetadata); !ok {
+			t.Errorf("expected directory metadata, but got: %T", m)
+		}
+
+		content, err := os.ReadFile(filepath.Join(destination, "dir", "file.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != "hello world" {
+			t.Errorf("expected content: hello world, but got: %s", content)
+		}
+	})
+
+	t.Run("File", func(t *testing.T) {
+		destination := filepath.Join(t.TempDir(), "file.txt")
+		m, err := GatherReader(ctx, bytes.NewReader([]byte("hello world")), "", destination)
+		if err != nil {
+			t.Fatalf("expected no error, but got: %s", err.Error())
+		}
+
+		fm, ok := m.(*file.FileMetadata)
+		if !ok {
+			t.Fatalf("expected file metadata, but got: %T", m)
+		}
+		if fm.Size != 11 {
+			t.Errorf("expected size: 11, but got: %d", fm.Size)
+		}
+		expectedSHA := "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
+		if fm.SHA != expectedSHA {
+			t.Errorf("expected SHA: %s, but got: %s", expectedSHA, fm.SHA)
+		}
+	})
+
+	t.Run("UnsupportedFormat", func(t *testing.T) {
+		_, err := GatherReader(ctx, bytes.NewReader(nil), "rar", filepath.Join(t.TempDir(), "out"))
+		if !errors.Is(err, ErrUnsupportedFormat) {
+			t.Errorf("expected error to wrap ErrUnsupportedFormat, but got: %v", err)
+		}
+	})
+}
+
+func TestDescribe(t *testing.T) {
+	info := Describe()
+
+	expected := map[string]string{
+		"DataURI":   "data",
+		"FileURI":   "file",
+		"GitURI":    "git",
+		"GitHubURI": "github",
+		"HTTPURI":   "http",
+		"OCIURI":    "oci",
+	}
+	if len(info) != len(expected) {
+		t.Errorf("expected %d gatherers to be described, but got: %d", len(expected), len(info))
+	}
+	for uriType, name := range expected {
+		i, ok := info[uriType]
+		if !ok {
+			t.Errorf("expected %s to be described", uriType)
+			continue
+		}
+		if i.Name != name {
+			t.Errorf("expected name: %s, but got: %s", name, i.Name)
+		}
+		if len(i.Prefixes) == 0 || i.Destination == "" {
+			t.Errorf("expected prefixes and destination to be described for %s, but got: %+v", uriType, i)
+		}
+	}
+}
+
+func TestRegisteredSchemes(t *testing.T) {
+	schemes := RegisteredSchemes()
+	if !sort.StringsAreSorted(schemes) {
+		t.Errorf("expected schemes to be sorted, but got: %v", schemes)
+	}
+
+	registered := map[string]bool{}
+	for _, scheme := range schemes {
+		registered[scheme] = true
+	}
+	for _, scheme := range []string{"data:", "file::", "git::", "github://", "http::", "https://", "oci::"} {
+		if !registered[scheme] {
+			t.Errorf("expected %s to be registered, but got: %v", scheme, schemes)
+		}
+	}
+}
+
+// TestRegisteredSchemes_GitHosts tests that a host added to GitHosts is advertised as a git source prefix.
+func TestRegisteredSchemes_GitHosts(t *testing.T) {
+	original := gogather.GitHosts
+	gogather.GitHosts = append([]string{"gitea.internal"}, gogather.GitHosts...)
+	t.Cleanup(func() { gogather.GitHosts = original })
+
+	schemes := RegisteredSchemes()
+	if i := sort.SearchStrings(schemes, "gitea.internal/"); i == len(schemes) || schemes[i] != "gitea.internal/" {
+		t.Errorf("expected gitea.internal/ to be registered, but got: %v", schemes)
+	}
+}
+
+func TestGatherReader_Timestamp(t *testing.T) {
+	fixed := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	original := gogather.NowFunc
+	gogather.NowFunc = func() time.Time { return fixed }
+	t.Cleanup(func() { gogather.NowFunc = original })
+
+	m, err := GatherReader(context.Background(), bytes.NewReader([]byte("hello world")), "", filepath.Join(t.TempDir(), "file.txt"))
+	if err != nil {
+		t.Fatalf("expected no error, but got: %s", err.Error())
+	}
+	fm, ok := m.(*file.FileMetadata)
+	if !ok {
+		t.Fatalf("expected file metadata, but got: %T", m)
+	}
+	if !fm.Timestamp.Equal(fixed) {
+		t.Errorf("expected timestamp: %s, but got: %s", fixed, fm.Timestamp)
+	}
+}
+
+func TestGather_InvalidDestination(t *testing.T) {
+	dir := t.TempDir()
+	parent := filepath.Join(dir, "file.txt")
+	if err := os.WriteFile(parent, []byte("content"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, source := range []string{dir, "git::https://example.com/org/repo.git", "https://example.com/file.txt", "quay.io/org/repo:latest"} {
+		t.Run(source, func(t *testing.T) {
+			_, err := Gather(context.Background(), source, filepath.Join(parent, "out"))
+			if !errors.Is(err, gogather.ErrInvalidDestination) {
+				t.Errorf("expected error to wrap ErrInvalidDestination, but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestHead_NotSupported(t *testing.T) {
+	_, err := Head(context.Background(), t.TempDir())
+	if !errors.Is(err, ErrHeadNotSupported) {
+		t.Errorf("expected error to wrap ErrHeadNotSupported, but got: %v", err)
+	}
+}
+
+func TestGather_Data(t *testing.T) {
+	destination := filepath.Join(t.TempDir(), "file.txt")
+	if _, err := Gather(context.Background(), "data:text/plain;base64,aGVsbG8=", destination); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	content, err := os.ReadFile(destination)
+	if err != nil || string(content) != "hello" {
+		t.Errorf("expected the decoded content to be written, but got: %q, %v", content, err)
+	}
+}
+
+func TestGatherWith(t *testing.T) {
+	// A file named like a git repository is classified as one, so Gather fails to clone it
+	dir := t.TempDir()
+	source := filepath.Join(dir, "policy.git")
+	if err := os.WriteFile(source, []byte("content"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Gather(context.Background(), source, filepath.Join(dir, "cloned")); err == nil {
+		t.Fatal("expected an error cloning a file, but got nil")
+	}
+
+	destination := filepath.Join(dir, "copied.txt")
+	if _, err := GatherWith(context.Background(), gogather.FileURI, source, "file://"+destination); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if content, err := os.ReadFile(destination); err != nil || string(content) != "content" {
+		t.Errorf("expected the file to be copied, but got: %q, %v", content, err)
+	}
+
+	if _, err := GatherWith(context.Background(), gogather.Unknown, source, destination); !errors.Is(err, ErrNoGatherer) {
+		t.Errorf("expected error to wrap ErrNoGatherer, but got: %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		source string
+		err    error
+	}{
+		{source: "git::https://github.com/org/repo.git?ref=main&depth=1"},
+		{source: "oci://registry.example.com/org/policy:v1"},
+		{source: "https://example.com/policy.tar.gz"},
+		{source: t.TempDir()},
+		{source: "git::https://github.com/org/repo.git?ref=feature..x", err: gitGather.ErrInvalidSource},
+		{source: "git::https://github.com/org/repo.git?depth=many", err: gitGather.ErrInvalidSource},
+		{source: "git::https://github.com/org/repo.git//../policy", err: gitGather.ErrInvalidSubdir},
+		{source: "oci://registry.example.com/Org/policy:v1", err: ociGather.ErrInvalidReference},
+		{source: "oci://registry.example.com/org/policy@sha256:short", err: ociGather.ErrInvalidReference},
+		{source: "http::https://", err: httpGather.ErrInvalidSource},
+	}
+
+	for _, tc := range testCases {
+		err := Validate(tc.source)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("expected Validate(%q) to return %v, but got: %v", tc.source, tc.err, err)
+		}
+	}
+}
+
+func TestSelfCheck(t *testing.T) {
+	if err := SelfCheck(); err != nil {
+		t.Fatalf("expected the built-in gatherers to be registered, but got: %v", err)
+	}
+
+	original := protocolHandlers
+	t.Cleanup(func() { protocolHandlers = original })
+	protocolHandlers = map[string]Gatherer{"FileURI": original["FileURI"], "HTTPURI": original["HTTPURI"]}
+
+	err := SelfCheck()
+	if !errors.Is(err, ErrMissingGatherer) {
+		t.Fatalf("expected error to wrap ErrMissingGatherer, but got: %v", err)
+	}
+	if err.Error() != "missing gatherer: GitURI, OCIURI" {
+		t.Errorf("expected the missing gatherers to be listed, but got: %v", err)
+	}
+}
+
+// TestSelfCheck_MissingExpander tests that SelfCheck reports the archive formats without an expander.
+func TestSelfCheck_MissingExpander(t *testing.T) {
+	original := baseExpanders
+	t.Cleanup(func() { baseExpanders = original })
+	baseExpanders = func(int, int64) map[string]expander.Expander { return map[string]expander.Expander{} }
+
+	err := SelfCheck()
+	if !errors.Is(err, ErrMissingExpander) {
+		t.Fatalf("expected error to wrap ErrMissingExpander, but got: %v", err)
+	}
+	if errors.Is(err, ErrMissingGatherer) {
+		t.Errorf("expected no missing gatherers, but got: %v", err)
+	}
+	if err.Error() != "missing expander: tar" {
+		t.Errorf("expected the missing expanders to be listed, but got: %v", err)
+	}
+}
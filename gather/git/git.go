@@ -21,19 +21,30 @@ package git
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/url"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
 	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/go-git/go-git/v5/storage/memory"
 	gitUrls "github.com/whilp/git-urls"
 
 	gogather "github.com/enterprise-contract/go-gather"
@@ -41,13 +52,56 @@ import (
 	gitMetadata "github.com/enterprise-contract/go-gather/metadata/git"
 )
 
+var (
+	// ErrPathNotFound is returned when the requested path does not exist in the repository.
+	ErrPathNotFound = errors.New("path does not exist in the repository")
+	// ErrInvalidSubdir is returned when the requested path is absolute or would escape the repository.
+	ErrInvalidSubdir = errors.New("invalid path within the repository")
+	// ErrRefNotFound is returned by ResolveRef when the repository has no branch or tag with the requested name.
+	ErrRefNotFound = errors.New("reference not found in the repository")
+	// ErrInvalidSource is returned by Validate when the source cannot be parsed, or has an invalid ref or depth.
+	ErrInvalidSource = errors.New("invalid git source")
+	// ErrCloneTooLarge is returned when the gathered repository is larger than the configured MaxCloneBytes.
+	ErrCloneTooLarge = errors.New("cloned repository too large")
+	// ErrPathIsSymlink is returned when the requested path is a symbolic link in the repository.
+	ErrPathIsSymlink = errors.New("path is a symbolic link in the repository")
+)
+
 // GitGatherer is a struct that implements the Gatherer interface
 // and provides methods for gathering git repositories.
 type GitGatherer struct {
 	// Authenticator is an SSHAuthenticator that provides authentication for SSH connections.
 	Authenticator SSHAuthenticator
+	// Strategy determines where the repository is cloned to when only a path within it is gathered.
+	Strategy CloneStrategy
+	// Offline makes Gather fail immediately with gogather.ErrNetworkDisabled.
+	Offline bool
+	// CloneTimeout limits how long cloning the repository may take, independently of the context passed to Gather.
+	// Zero means no limit.
+	CloneTimeout time.Duration
+	// RefFallback makes Gather clone the default branch, logging a warning, when the ref of the source is not found.
+	// The Ref of the returned metadata is then the default branch. Otherwise, a missing ref fails the Gather.
+	RefFallback bool
+	// MaxCloneBytes is the maximum total size, in bytes, of the files gathered into the destination, including
+	// the repository's .git directory. A larger gather fails with ErrCloneTooLarge, and a destination created by
+	// it is removed. Zero means no limit.
+	MaxCloneBytes int64
+	// CredentialHelper makes Gather ask the git credential helpers configured for the user, with "git credential fill",
+	// for the username and password to clone an http or https source with. If git is not installed, or no helper
+	// provides credentials, the source is cloned without them.
+	CredentialHelper bool
 }
 
+// CloneStrategy determines where a repository is cloned to when only a path within it is gathered.
+type CloneStrategy int
+
+const (
+	// DiskClone clones the repository into a temporary directory before copying the requested path.
+	DiskClone CloneStrategy = iota
+	// MemoryClone clones the repository into memory and writes only the requested path to disk.
+	MemoryClone
+)
+
 // SSHAuthenticator represents an interface for authenticating SSH connections.
 type SSHAuthenticator interface {
 	// NewSSHAgentAuth returns a new SSH agent authentication method for the given user.
@@ -64,14 +118,41 @@ func (r *RealSSHAuthenticator) NewSSHAgentAuth(user string) (transport.AuthMetho
 	return ssh.NewSSHAgentAuth(user)
 }
 
+// Describe returns information about the sources handled by the GitGatherer and how it writes them.
+// The prefixes include those of the hosts in gogather.GitHosts, e.g. "github.com/".
+func (g *GitGatherer) Describe() gogather.GathererInfo {
+	prefixes := []string{"git::", "git@", "ssh://"}
+	for _, host := range gogather.GitHosts {
+		prefixes = append(prefixes, host+"/")
+	}
+	return gogather.GathererInfo{
+		Name:        "git",
+		Prefixes:    prefixes,
+		Extracts:    false,
+		Destination: "the repository is cloned into the destination directory; a file within it, selected with //path, is written to the destination path, or into it if it is a directory or ends with a separator",
+	}
+}
+
 // Gather clones a Git repository from the given source URI into the specified destination directory,
 // and returns the metadata of the cloned repository.
 func (g *GitGatherer) Gather(ctx context.Context, source, destination string) (metadata.Metadata, error) {
+	if g.Offline {
+		return nil, fmt.Errorf("%w: cannot gather %s", gogather.ErrNetworkDisabled, source)
+	}
+
+	if err := gogather.ValidateDestination(destination); err != nil {
+		return nil, err
+	}
+
 	src, ref, subdir, depth, err := processUrl(source)
 	if err != nil {
 		return nil, fmt.Errorf("failed to process URL: %w", err)
 	}
 
+	if err := validateSubdir(subdir); err != nil {
+		return nil, err
+	}
+
 	cloneOpts := &git.CloneOptions{
 		URL: src,
 	}
@@ -80,6 +161,18 @@ func (g *GitGatherer) Gather(ctx context.Context, source, destination string) (m
 		cloneOpts.InsecureSkipTLS = true
 	}
 
+	if g.Authenticator != nil && (strings.HasPrefix(src, "ssh://") || strings.HasPrefix(src, "git@")) {
+		if cloneOpts.Auth, err = g.Authenticator.NewSSHAgentAuth("git"); err != nil {
+			return nil, fmt.Errorf("failed to create SSH auth method: %w", err)
+		}
+	}
+
+	if g.CredentialHelper && (strings.HasPrefix(src, "https://") || strings.HasPrefix(src, "http://")) {
+		if auth := credentialHelperAuth(ctx, src); auth != nil {
+			cloneOpts.Auth = auth
+		}
+	}
+
 	if ref != "" {
 		cloneOpts.ReferenceName = plumbing.ReferenceName("refs/heads/" + ref)
 	}
@@ -92,9 +185,66 @@ func (g *GitGatherer) Gather(ctx context.Context, source, destination string) (m
 		cloneOpts.Depth = depth
 	}
 
-	// If we don't have a subdir, clone the repository and return the metadata
+	if g.CloneTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.CloneTimeout)
+		defer cancel()
+	}
+
+	_, statErr := os.Stat(destination)
+	existed := statErr == nil
+
+	m, err := g.clone(ctx, subdir, destination, cloneOpts)
+	if err != nil && ref != "" && errors.Is(err, plumbing.ErrReferenceNotFound) {
+		// The ref is not a branch, so it may be a tag, e.g. "v1.0.0". If it is neither, the branch error is kept.
+		tagOpts := *cloneOpts
+		tagOpts.ReferenceName = plumbing.NewTagReferenceName(ref)
+		tm, tagErr := g.clone(ctx, subdir, destination, &tagOpts)
+		if !errors.As(tagErr, &git.NoMatchingRefSpecError{}) && !errors.Is(tagErr, plumbing.ErrReferenceNotFound) {
+			m, err = tm, tagErr
+			if gm, ok := m.(*gitMetadata.GitMetadata); ok {
+				// A tag is checked out detached, so the clone does not record it as its HEAD
+				gm.Ref = tagOpts.ReferenceName.String()
+			}
+		}
+	}
+	if err != nil && g.RefFallback && ref != "" && errors.Is(err, plumbing.ErrReferenceNotFound) {
+		log.Printf("warning: ref %s not found in %s, falling back to the default branch", ref, src)
+		cloneOpts.ReferenceName = ""
+		m, err = g.clone(ctx, subdir, destination, cloneOpts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if g.MaxCloneBytes > 0 {
+		size, _, err := gogather.GetDirectorySize(destination)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get directory size: %w", err)
+		}
+		if size > g.MaxCloneBytes {
+			if !existed {
+				_ = os.RemoveAll(destination)
+			}
+			return nil, fmt.Errorf("%w: %d bytes exceeds the %d limit", ErrCloneTooLarge, size, g.MaxCloneBytes)
+		}
+	}
+	return m, nil
+}
+
+// clone clones the repository, or only its subdir if it is set, to the destination and returns the metadata.
+func (g *GitGatherer) clone(ctx context.Context, subdir, destination string, cloneOpts *git.CloneOptions) (metadata.Metadata, error) {
+	// If we don't have a subdir, clone the repository, or update an existing clone of it, and return the metadata
 	if subdir == "" {
-		r, err := git.PlainClone(destination, false, cloneOpts)
+		r, err := openClone(destination, cloneOpts.URL)
+		if err != nil {
+			return nil, err
+		}
+		if r != nil {
+			err = updateClone(ctx, r, cloneOpts)
+		} else {
+			r, err = git.PlainCloneContext(ctx, destination, false, cloneOpts)
+		}
 		if err != nil {
 			return nil, fmt.Errorf("error cloning repository: %w", err)
 		}
@@ -107,6 +257,9 @@ func (g *GitGatherer) Gather(ctx context.Context, source, destination string) (m
 
 		// Safely accumulate commits into the metadata structure
 		m := &gitMetadata.GitMetadata{}
+		if head, err := r.Head(); err == nil {
+			m.Ref = head.Name().String()
+		}
 		err = commits.ForEach(func(c *object.Commit) error {
 			m.Commits = append(m.Commits, *c)
 			return nil
@@ -120,10 +273,144 @@ func (g *GitGatherer) Gather(ctx context.Context, source, destination string) (m
 	}
 
 	// If we have a subdir, clone the repository and copy the subdir to the destination
+	if g.Strategy == MemoryClone {
+		return cloneRepositoryPathInMemory(ctx, subdir, destination, cloneOpts)
+	}
 	return cloneRepositoryPath(ctx, subdir, destination, cloneOpts)
 }
 
-// cloneRepositoryPath clones a git repository, copies the specified subdirectory to the destination, and returns the metadata.
+// Validate checks that source is a well-formed git source, with a valid ref, subdir and depth, without cloning it.
+func (g *GitGatherer) Validate(source string) error {
+	_, ref, subdir, depth, err := processUrl(source)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidSource, err)
+	}
+
+	if err := validateSubdir(subdir); err != nil {
+		return err
+	}
+
+	if ref != "" {
+		if err := plumbing.NewBranchReferenceName(ref).Validate(); err != nil {
+			return fmt.Errorf("%w: ref %q: %w", ErrInvalidSource, ref, err)
+		}
+	}
+
+	if depth != "" {
+		if d, err := strconv.Atoi(depth); err != nil || d < 0 {
+			return fmt.Errorf("%w: depth %q is not a non-negative integer", ErrInvalidSource, depth)
+		}
+	}
+	return nil
+}
+
+// credentialHelperAuth returns the username and password that the git credential helpers configured for the user
+// provide for the repository at repoURL, or nil if git is not installed or they provide none.
+func credentialHelperAuth(ctx context.Context, repoURL string) *githttp.BasicAuth {
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return nil
+	}
+
+	cmd := exec.CommandContext(ctx, "git", "credential", "fill")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("protocol=%s\nhost=%s\npath=%s\n\n", u.Scheme, u.Host, strings.TrimPrefix(u.Path, "/")))
+	// Fail rather than prompt for credentials no helper provides
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_ASKPASS=", "SSH_ASKPASS=")
+	out, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+
+	auth := &githttp.BasicAuth{}
+	for _, line := range strings.Split(string(out), "\n") {
+		key, value, _ := strings.Cut(line, "=")
+		switch key {
+		case "username":
+			auth.Username = value
+		case "password":
+			auth.Password = value
+		}
+	}
+	if auth.Username == "" && auth.Password == "" {
+		return nil
+	}
+	return auth
+}
+
+// openClone returns the repository at destination if it is a clone of url, or nil if there is no repository there.
+// A repository cloned from elsewhere is reported as git.ErrRepositoryAlreadyExists, as cloning into it would be.
+func openClone(destination, url string) (*git.Repository, error) {
+	r, err := git.PlainOpen(destination)
+	if err != nil {
+		return nil, nil
+	}
+
+	remote, err := r.Remote(git.DefaultRemoteName)
+	if err != nil || len(remote.Config().URLs) == 0 || remote.Config().URLs[0] != url {
+		return nil, fmt.Errorf("error cloning repository: %w: %s is not a clone of %s", git.ErrRepositoryAlreadyExists, destination, url)
+	}
+	return r, nil
+}
+
+// updateClone fetches the branch or tag of cloneOpts, or the branch checked out if it has none, into the existing
+// clone r, and checks out what was fetched, discarding any local changes.
+func updateClone(ctx context.Context, r *git.Repository, cloneOpts *git.CloneOptions) error {
+	fetchOpts := &git.FetchOptions{
+		RemoteName:      git.DefaultRemoteName,
+		Depth:           cloneOpts.Depth,
+		Auth:            cloneOpts.Auth,
+		InsecureSkipTLS: cloneOpts.InsecureSkipTLS,
+		Force:           true,
+	}
+	if cloneOpts.ReferenceName.IsTag() {
+		fetchOpts.Tags = git.AllTags
+	}
+	err := r.FetchContext(ctx, fetchOpts)
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("error fetching repository: %w", err)
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		return fmt.Errorf("error opening worktree: %w", err)
+	}
+
+	// A tag is checked out detached, as a clone of it is
+	if tag := cloneOpts.ReferenceName; tag.IsTag() {
+		hash, err := r.ResolveRevision(plumbing.Revision(tag))
+		if err != nil {
+			return fmt.Errorf("error resolving %s: %w", tag.Short(), err)
+		}
+		if err := w.Checkout(&git.CheckoutOptions{Hash: *hash, Force: true}); err != nil {
+			return fmt.Errorf("error checking out %s: %w", tag.Short(), err)
+		}
+		return nil
+	}
+
+	branch := cloneOpts.ReferenceName
+	if branch == "" {
+		head, err := r.Reference(plumbing.HEAD, false)
+		if err != nil {
+			return fmt.Errorf("error reading HEAD: %w", err)
+		}
+		branch = head.Target()
+	}
+
+	fetched, err := r.Reference(plumbing.NewRemoteReferenceName(git.DefaultRemoteName, branch.Short()), true)
+	if err != nil {
+		return fmt.Errorf("error resolving %s: %w", branch.Short(), err)
+	}
+	if err := r.Storer.SetReference(plumbing.NewHashReference(branch, fetched.Hash())); err != nil {
+		return fmt.Errorf("error updating %s: %w", branch.Short(), err)
+	}
+
+	if err := w.Checkout(&git.CheckoutOptions{Branch: branch, Force: true}); err != nil {
+		return fmt.Errorf("error checking out %s: %w", branch.Short(), err)
+	}
+	return nil
+}
+
+// cloneRepositoryPath clones a git repository, copies the specified subdirectory or file to the destination, and returns the metadata.
 func cloneRepositoryPath(ctx context.Context, path, destination string, cloneOpts *git.CloneOptions) (metadata.Metadata, error) {
 	// create a temporary directory to clone the repository into
 	tmpDir, err := os.MkdirTemp("", "git-repo-")
@@ -132,29 +419,92 @@ func cloneRepositoryPath(ctx context.Context, path, destination string, cloneOpt
 	}
 	defer os.RemoveAll(tmpDir)
 
-	// Clone the repository into the temporary directory
-	r, err := git.PlainCloneContext(ctx, tmpDir, false, cloneOpts)
+	// Clone the repository into the temporary directory, deferring the checkout until we know what was requested
+	opts := *cloneOpts
+	opts.NoCheckout = true
+	r, err := git.PlainCloneContext(ctx, tmpDir, false, &opts)
 	if err != nil {
 		return nil, fmt.Errorf("error cloning repository: %w", err)
 	}
 
-	// Get the worktree
-	w, err := r.Worktree()
+	return gatherRepositoryPath(r, path, destination, func(_ *git.Worktree, path string) error {
+		return copyDir(filepath.Join(tmpDir, path), destination)
+	})
+}
+
+// cloneRepositoryPathInMemory clones a git repository into memory, writes the specified subdirectory or file to the destination,
+// and returns the metadata. Nothing but the requested path is written to disk.
+func cloneRepositoryPathInMemory(ctx context.Context, path, destination string, cloneOpts *git.CloneOptions) (metadata.Metadata, error) {
+	opts := *cloneOpts
+	opts.NoCheckout = true
+	r, err := git.CloneContext(ctx, memory.NewStorage(), memfs.New(), &opts)
 	if err != nil {
-		return nil, fmt.Errorf("error getting worktree: %w", err)
+		return nil, fmt.Errorf("error cloning repository: %w", err)
 	}
 
-	// Check if the path exists in the repository
-	_, err = w.Filesystem.Stat(path)
+	return gatherRepositoryPath(r, path, destination, func(w *git.Worktree, path string) error {
+		return copyFromFilesystem(w.Filesystem, path, destination)
+	})
+}
+
+// gatherRepositoryPath writes the specified subdirectory or file of a cloned repository to the destination, and returns the metadata.
+// The repository is expected to have been cloned without a checkout; a single file is read directly from the commit tree, and the
+// worktree is only checked out when a directory has been requested, after which copyDir is used to copy it to the destination.
+func gatherRepositoryPath(r *git.Repository, path, destination string, copyDir func(w *git.Worktree, path string) error) (metadata.Metadata, error) {
+	// Resolve the tree of the commit the reference points to
+	head, err := r.Head()
+	if err != nil {
+		return nil, fmt.Errorf("error resolving HEAD: %w", err)
+	}
+	commit, err := r.CommitObject(head.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("error getting commit: %w", err)
+	}
+	tree, err := commit.Tree()
 	if err != nil {
-		return nil, fmt.Errorf("path %s does not exist in the repository", path)
+		return nil, fmt.Errorf("error getting commit tree: %w", err)
 	}
 
-	path = filepath.Join(tmpDir, path)
+	// Check if the path exists in the repository. The root, ".", is the whole repository.
+	path = strings.Trim(filepath.ToSlash(path), "/")
+	if path == "." {
+		path = ""
+	}
+	var entry *object.TreeEntry
+	if path != "" {
+		if entry, err = tree.FindEntry(path); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrPathNotFound, path)
+		}
+	}
 
-	err = copyDir(path, destination)
-	if err != nil {
-		return nil, fmt.Errorf("error copying directory: %w", err)
+	// The target of a link is relative to where it is in the repository, so it cannot be written elsewhere
+	if entry != nil && entry.Mode == filemode.Symlink {
+		return nil, fmt.Errorf("%w: %s", ErrPathIsSymlink, path)
+	}
+
+	if entry != nil && (entry.Mode == filemode.Regular || entry.Mode == filemode.Executable) {
+		// Write just the requested blob, no checkout required
+		f, err := tree.TreeEntryFile(entry)
+		if err != nil {
+			return nil, fmt.Errorf("error getting file %s: %w", path, err)
+		}
+		if err := writeBlob(f, fileDestination(path, destination)); err != nil {
+			return nil, fmt.Errorf("error writing file: %w", err)
+		}
+	} else {
+		// Check out the worktree so the directory can be copied
+		w, err := r.Worktree()
+		if err != nil {
+			return nil, fmt.Errorf("error getting worktree: %w", err)
+		}
+		if err := w.Reset(&git.ResetOptions{Commit: head.Hash(), Mode: git.HardReset}); err != nil {
+			return nil, fmt.Errorf("error checking out worktree: %w", err)
+		}
+
+		err = copyDir(w, path)
+		if err != nil {
+			return nil, fmt.Errorf("error copying directory: %w", err)
+		}
 	}
 
 	// Get the commit history
@@ -164,7 +514,7 @@ func cloneRepositoryPath(ctx context.Context, path, destination string, cloneOpt
 	}
 
 	// Safely accumulate commits into the metadata structure
-	m := &gitMetadata.GitMetadata{}
+	m := &gitMetadata.GitMetadata{Ref: head.Name().String()}
 	err = commits.ForEach(func(c *object.Commit) error {
 		m.Commits = append(m.Commits, *c)
 		return nil
@@ -208,6 +558,11 @@ func copyDir(src string, dst string) error {
 	}
 
 	for _, entry := range entries {
+		// A repository cannot hold a .git directory, so it is that of the clone
+		if entry.Name() == git.GitDirName {
+			continue
+		}
+
 		srcPath := filepath.Join(src, entry.Name())
 		dstPath := filepath.Join(dst, entry.Name())
 
@@ -252,12 +607,94 @@ func copyFile(src string, dst string) error {
 	return os.Chmod(dst, srcInfo.Mode())
 }
 
+// copyFromFilesystem copies the contents of the src directory within fs to the dst directory on disk
+func copyFromFilesystem(fs billy.Filesystem, src string, dst string) error {
+	entries, err := fs.ReadDir(src)
+	if err != nil {
+		return fmt.Errorf("error reading source directory: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Clean(dst), 0755); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		srcPath := fs.Join(src, entry.Name())
+		dstPath := filepath.Join(dst, entry.Name())
+
+		if entry.IsDir() {
+			err = copyFromFilesystem(fs, srcPath, dstPath)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		srcFile, err := fs.Open(srcPath)
+		if err != nil {
+			return err
+		}
+		dstFile, err := os.OpenFile(filepath.Clean(dstPath), os.O_RDWR|os.O_CREATE|os.O_TRUNC, entry.Mode().Perm())
+		if err != nil {
+			srcFile.Close()
+			return err
+		}
+		_, err = io.Copy(dstFile, srcFile)
+		srcFile.Close()
+		dstFile.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fileDestination returns the path a single file from the repository should be written to.
+// If the destination is an existing directory, or ends with a path separator, the file is
+// written into it using its base name. Otherwise the destination is used as the file path.
+func fileDestination(path, destination string) string {
+	if strings.HasSuffix(destination, "/") || strings.HasSuffix(destination, string(os.PathSeparator)) {
+		return filepath.Join(destination, filepath.Base(path))
+	}
+	if info, err := os.Stat(destination); err == nil && info.IsDir() {
+		return filepath.Join(destination, filepath.Base(path))
+	}
+	return destination
+}
+
+// writeBlob writes the contents of a file from the repository tree to dst
+func writeBlob(f *object.File, dst string) error {
+	mode, err := f.Mode.ToOSFileMode()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	reader, err := f.Reader()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	dstFile, err := os.OpenFile(filepath.Clean(dst), os.O_RDWR|os.O_CREATE|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return err
+	}
+	defer dstFile.Close()
+
+	_, err = io.Copy(dstFile, reader)
+	return err
+}
+
 // extractSubdirFromQuery extracts the value of the key from the query parameters and extracts a subdir, if present.
 func extractSubdirFromQuery(q url.Values, key string, subdir *string) string {
 	value := q.Get(key)
 	if strings.Contains(value, "//") {
 		parts := strings.SplitN(value, "//", 2)
-		*subdir = parts[1]
+		*subdir = rootSubdir(parts[1])
 		q.Del(key)
 		return parts[0]
 	}
@@ -265,6 +702,83 @@ func extractSubdirFromQuery(q url.Values, key string, subdir *string) string {
 	return value
 }
 
+// rootSubdir returns the subdir following "//" in a source, or "." for the root of the repository if it is empty.
+func rootSubdir(subdir string) string {
+	if subdir == "" {
+		return "."
+	}
+	return subdir
+}
+
+// ResolveRef looks up ref, a branch, tag or full reference name, in the repository at src without cloning it,
+// and returns the hash of the commit it refers to. Annotated tags are resolved to the commit they tag.
+func (g *GitGatherer) ResolveRef(ctx context.Context, src, ref string) (string, error) {
+	if g.Offline {
+		return "", fmt.Errorf("%w: cannot resolve %s", gogather.ErrNetworkDisabled, src)
+	}
+
+	repoURL, _, _, _, err := processUrl(src)
+	if err != nil {
+		return "", fmt.Errorf("failed to process URL: %w", err)
+	}
+
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{Name: git.DefaultRemoteName, URLs: []string{repoURL}})
+	listOpts := &git.ListOptions{
+		PeelingOption:   git.AppendPeeled,
+		InsecureSkipTLS: os.Getenv("GIT_SSL_NO_VERIFY") == "true",
+	}
+	if g.Authenticator != nil && (strings.HasPrefix(repoURL, "ssh://") || strings.HasPrefix(repoURL, "git@")) {
+		if listOpts.Auth, err = g.Authenticator.NewSSHAgentAuth("git"); err != nil {
+			return "", fmt.Errorf("failed to create SSH auth method: %w", err)
+		}
+	}
+
+	refs, err := remote.ListContext(ctx, listOpts)
+	if err != nil {
+		return "", fmt.Errorf("failed to list references: %w", err)
+	}
+
+	hashes := make(map[string]string, len(refs))
+	for _, r := range refs {
+		hashes[r.Name().String()] = r.Hash().String()
+	}
+	// A symbolic reference, such as HEAD, is listed with the name of the reference it points to
+	for _, r := range refs {
+		if r.Type() == plumbing.SymbolicReference {
+			hashes[r.Name().String()] = hashes[r.Target().String()]
+		}
+	}
+
+	for _, name := range []string{ref, "refs/heads/" + ref, "refs/tags/" + ref} {
+		// A peeled tag is listed with the hash of the commit it tags
+		if hash, ok := hashes[name+"^{}"]; ok {
+			return hash, nil
+		}
+		if hash, ok := hashes[name]; ok {
+			return hash, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s in %s", ErrRefNotFound, ref, repoURL)
+}
+
+// Head resolves the ref of source, or HEAD if it has none, without cloning the repository, and returns
+// metadata holding the commit it refers to.
+func (g *GitGatherer) Head(ctx context.Context, source string) (metadata.Metadata, error) {
+	_, ref, _, _, err := processUrl(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to process URL: %w", err)
+	}
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	hash, err := g.ResolveRef(ctx, source, ref)
+	if err != nil {
+		return nil, err
+	}
+	return &gitMetadata.GitMetadata{Commits: []object.Commit{{Hash: plumbing.NewHash(hash)}}}, nil
+}
+
 // getGitCloneOptions returns the clone options for the git repository.
 func getCloneOptions(source string, auth SSHAuthenticator) (*git.CloneOptions, error) {
 	src, err := gitUrls.Parse(source)
@@ -292,12 +806,89 @@ func getCloneOptions(source string, auth SSHAuthenticator) (*git.CloneOptions, e
 	return cloneOpts, nil
 }
 
-// processUrl processes the raw URL and returns the source URL, ref, subdir, and depth.
-func processUrl(rawURL string) (src, ref, subdir, depth string, err error) {
+// validateSubdir returns ErrInvalidSubdir if the subdir is an absolute path or contains a ".." component,
+// either of which could be used to read files outside of the cloned repository.
+func validateSubdir(subdir string) error {
+	if strings.HasPrefix(subdir, "/") || strings.HasPrefix(subdir, `\`) || filepath.IsAbs(subdir) {
+		return fmt.Errorf("%w: %s is an absolute path", ErrInvalidSubdir, subdir)
+	}
+	for _, part := range strings.FieldsFunc(subdir, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if part == ".." {
+			return fmt.Errorf("%w: %s contains \"..\"", ErrInvalidSubdir, subdir)
+		}
+	}
+	return nil
+}
+
+// localRepositoryPath returns the path of the local repository referred to by a git::/path, git:///path
+// or git::file:///path URL, and whether the URL refers to a local repository.
+func localRepositoryPath(rawURL string) (string, bool) {
+	path := strings.TrimPrefix(rawURL, "git::")
+	path = strings.TrimPrefix(path, "file://")
+	if path == rawURL {
+		path = strings.TrimPrefix(rawURL, "git://")
+	}
+	return path, path != rawURL && strings.HasPrefix(path, "/")
+}
+
+// processLocalPath returns the path, ref, subdir, and depth of a local repository from its path, which
+// may specify a subdir after "//" and the ref and depth as query parameters or the ref as a fragment.
+func processLocalPath(path string) (src, ref, subdir, depth string, err error) {
+	u, err := url.Parse("file://" + path)
+	if err != nil {
+		return src, ref, subdir, depth, fmt.Errorf("failed to parse path: %w", err)
+	}
+
+	q := u.Query()
+	ref = extractSubdirFromQuery(q, "ref", &subdir)
+	depth = extractSubdirFromQuery(q, "depth", &subdir)
+	if ref == "" {
+		ref = u.Fragment
+	}
+
+	src = u.Path
+	if strings.Contains(src, "//") {
+		parts := strings.SplitN(src, "//", 2)
+		src, subdir = parts[0], rootSubdir(parts[1])
+	}
+
+	return src, ref, subdir, depth, nil
+}
+
+// scpURLPattern matches an scp-like git URL, e.g. "git@github.com:org/repo.git", capturing the user and host, and the path.
+var scpURLPattern = regexp.MustCompile(`^([\w.\-]+@[\w.\-]+):(.*)$`)
+
+// GitURL is a git source parsed by ParseGitURL.
+type GitURL struct {
+	// Repo is the URL the repository is cloned from, or its path if it is a local repository.
+	Repo string
+	// Ref is the branch or tag to clone, from the "ref" query parameter or the fragment of the source.
+	Ref string
+	// Subdir is the path within the repository to gather, following "//" in the source. It is "." if nothing follows
+	// the "//", which gathers the files of the whole repository without its .git directory.
+	Subdir string
+	// Depth is the number of commits to clone, from the "depth" query parameter.
+	Depth string
+	// Scheme is the scheme of Repo, e.g. "https" or "ssh", or "file" for a local repository.
+	Scheme string
+	// Auth is the kind of authentication the repository is cloned with: "ssh" for an SSH source, or "" if none.
+	Auth string
+}
+
+// ParseGitURL parses a git source, such as "git::https://github.com/org/repo//policy?ref=main",
+// "git@github.com:org/repo.git" or "github.com/org/repo#v1.0.0", without accessing the repository.
+// A remote repository URL is given a ".git" suffix, and one without a scheme is cloned over HTTPS.
+func ParseGitURL(rawURL string) (GitURL, error) {
+	// A local path is cloned from the filesystem, rather than being rewritten to an HTTPS URL
+	if path, ok := localRepositoryPath(rawURL); ok {
+		src, ref, subdir, depth, err := processLocalPath(path)
+		return GitURL{Repo: src, Ref: ref, Subdir: subdir, Depth: depth, Scheme: "file"}, err
+	}
+
 	// Check if the URL is a git URL and if it is not a SSH URL, convert it to HTTPS
 	t, err := gogather.ClassifyURI(rawURL)
 	if err != nil {
-		return src, ref, subdir, depth, fmt.Errorf("failed to classify URI: %w", err)
+		return GitURL{}, fmt.Errorf("failed to classify URI: %w", err)
 	}
 
 	// Check if the rawURL contains "::" and split it to get the actual URL if it does
@@ -309,29 +900,41 @@ func processUrl(rawURL string) (src, ref, subdir, depth string, err error) {
 		rawURL = "https://" + rawURL
 	}
 
+	// Rewrite an scp-like URL as an ssh:// URL, so its path and query are percent-decoded like those of other URLs
+	if m := scpURLPattern.FindStringSubmatch(rawURL); m != nil && !strings.Contains(rawURL, "://") {
+		rawURL = "ssh://" + m[1] + "/" + m[2]
+	}
+
 	// Parse the raw URL with the gitUrls package. This will format the URL correctly
 	parsedURL, err := gitUrls.Parse(rawURL)
 	if err != nil {
-		return src, ref, subdir, depth, fmt.Errorf("failed to parse URL: %w", err)
+		return GitURL{}, fmt.Errorf("failed to parse URL: %w", err)
 	}
 
 	// Parse the URL again with the url package to extract the query parameters, etc.
 	u, err := url.Parse(parsedURL.String())
 	if err != nil {
-		return src, ref, subdir, depth, fmt.Errorf("failed to reparse URL: %w", err)
+		return GitURL{}, fmt.Errorf("failed to reparse URL: %w", err)
 	}
 
 	// Extract the ref, subdir, and depth from the query parameters
+	var subdir string
 	q := u.Query()
-	ref = extractSubdirFromQuery(q, "ref", &subdir)
-	depth = extractSubdirFromQuery(q, "depth", &subdir)
+	ref := extractSubdirFromQuery(q, "ref", &subdir)
+	depth := extractSubdirFromQuery(q, "depth", &subdir)
 	u.RawQuery = q.Encode()
 
+	// Fall back to the fragment for the ref (e.g. "host/org/repo#v1.2.3"); the query parameter takes precedence
+	if ref == "" {
+		ref = u.Fragment
+	}
+	u.Fragment = ""
+
 	// If the path contains "//", split it to get the actual path and subdir
 	if strings.Contains(u.Path, "//") {
 		parts := strings.SplitN(u.Path, "//", 2)
 		u.Path = parts[0]
-		subdir = parts[1]
+		subdir = rootSubdir(parts[1])
 	}
 
 	// If the path does not end with ".git", append it
@@ -339,6 +942,15 @@ func processUrl(rawURL string) (src, ref, subdir, depth string, err error) {
 		u.Path += ".git"
 	}
 
-	// Return the URL, ref, subdir, and depth
-	return u.String(), ref, subdir, depth, nil
+	var auth string
+	if u.Scheme == "ssh" {
+		auth = "ssh"
+	}
+	return GitURL{Repo: u.String(), Ref: ref, Subdir: subdir, Depth: depth, Scheme: u.Scheme, Auth: auth}, nil
+}
+
+// processUrl processes the raw URL and returns the source URL, ref, subdir, and depth, see ParseGitURL.
+func processUrl(rawURL string) (src, ref, subdir, depth string, err error) {
+	u, err := ParseGitURL(rawURL)
+	return u.Repo, u.Ref, u.Subdir, u.Depth, err
 }
@@ -19,19 +19,25 @@ package git
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
+	gogather "github.com/enterprise-contract/go-gather"
+	"github.com/enterprise-contract/go-gather/metadata"
 	gitMetadata "github.com/enterprise-contract/go-gather/metadata/git"
 )
 
@@ -110,6 +116,18 @@ func TestGetGitCloneOptions_SSHAuthError(t *testing.T) {
 	mockAuth.AssertExpectations(t)
 }
 
+// TestGitGatherer_Gather_SSHScheme tests that an ssh:// source is cloned with the SSH authentication of the Authenticator
+func TestGitGatherer_Gather_SSHScheme(t *testing.T) {
+	mockAuth := new(MockSSHAuthenticator)
+	mockAuth.On("NewSSHAgentAuth", "git").Return(nil, fmt.Errorf("ssh auth error"))
+
+	g := &GitGatherer{Authenticator: mockAuth}
+	_, err := g.Gather(context.Background(), "ssh://git@example.com/org/repo", filepath.Join(t.TempDir(), "repo"))
+
+	assert.EqualError(t, err, "failed to create SSH auth method: ssh auth error")
+	mockAuth.AssertExpectations(t)
+}
+
 // TestGatherSuccess tests the successful gathering of a git repository
 func TestGatherSuccess(t *testing.T) {
 	// Create a temporary directory for the repository
@@ -269,3 +287,640 @@ func TestCloneRepositoryPath(t *testing.T) {
 		t.Fatalf("unexpected commit hash in metadata: %s", gitMetadata.Commits[0].Hash.String())
 	}
 }
+
+// createTestRepo initializes a git repository in a temporary directory containing the given files,
+// commits them, and returns the repository path and the commit hash.
+func createTestRepo(t *testing.T, files map[string]string) (string, plumbing.Hash) {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Add("."); err != nil {
+		t.Fatal(err)
+	}
+	commit, err := w.Commit("Initial commit", &git.CommitOptions{
+		Author: &object.Signature{
+			Name:  "Test User",
+			Email: "test@example.com",
+			When:  time.Now(),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return dir, commit
+}
+
+// TestCloneRepositoryPath_SingleFile tests that a single file can be gathered from a repository
+func TestCloneRepositoryPath_SingleFile(t *testing.T) {
+	sourceRepo, _ := createTestRepo(t, map[string]string{
+		"policy/config.yaml": "key: value",
+		"policy/other.yaml":  "other: value",
+		"README.md":          "readme",
+	})
+
+	t.Run("destination directory", func(t *testing.T) {
+		destination := t.TempDir()
+
+		_, err := cloneRepositoryPath(context.Background(), "policy/config.yaml", destination, &git.CloneOptions{URL: sourceRepo})
+		assert.NoError(t, err)
+
+		entries, err := os.ReadDir(destination)
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+		assert.Equal(t, "config.yaml", entries[0].Name())
+
+		content, err := os.ReadFile(filepath.Join(destination, "config.yaml"))
+		assert.NoError(t, err)
+		assert.Equal(t, "key: value", string(content))
+	})
+
+	t.Run("destination file", func(t *testing.T) {
+		destination := filepath.Join(t.TempDir(), "out", "renamed.yaml")
+
+		_, err := cloneRepositoryPath(context.Background(), "policy/config.yaml", destination, &git.CloneOptions{URL: sourceRepo})
+		assert.NoError(t, err)
+
+		content, err := os.ReadFile(destination)
+		assert.NoError(t, err)
+		assert.Equal(t, "key: value", string(content))
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := cloneRepositoryPath(context.Background(), "policy/missing.yaml", t.TempDir(), &git.CloneOptions{URL: sourceRepo})
+		assert.ErrorIs(t, err, ErrPathNotFound)
+		assert.EqualError(t, err, "path does not exist in the repository: policy/missing.yaml")
+	})
+}
+
+// TestCloneRepositoryPath_Symlink tests that a path that is a symbolic link in the repository is not gathered
+func TestCloneRepositoryPath_Symlink(t *testing.T) {
+	sourceRepo, _ := createTestRepo(t, map[string]string{"policy/config.yaml": "key: value"})
+	r, err := git.PlainOpen(sourceRepo)
+	assert.NoError(t, err)
+	assert.NoError(t, os.Symlink("policy/config.yaml", filepath.Join(sourceRepo, "link.yaml")))
+	w, err := r.Worktree()
+	assert.NoError(t, err)
+	_, err = w.Add("link.yaml")
+	assert.NoError(t, err)
+	_, err = w.Commit("Add a link", &git.CommitOptions{
+		Author: &object.Signature{Name: "Test User", Email: "test@example.com", When: time.Now()},
+	})
+	assert.NoError(t, err)
+
+	for name, clone := range map[string]func(context.Context, string, string, *git.CloneOptions) (metadata.Metadata, error){
+		"disk":   cloneRepositoryPath,
+		"memory": cloneRepositoryPathInMemory,
+	} {
+		t.Run(name, func(t *testing.T) {
+			destination := t.TempDir()
+			_, err := clone(context.Background(), "link.yaml", destination, &git.CloneOptions{URL: sourceRepo})
+			assert.ErrorIs(t, err, ErrPathIsSymlink)
+
+			entries, err := os.ReadDir(destination)
+			assert.NoError(t, err)
+			assert.Empty(t, entries)
+		})
+	}
+}
+
+// TestCloneRepositoryPathInMemory tests gathering paths from a repository cloned into memory
+func TestCloneRepositoryPathInMemory(t *testing.T) {
+	sourceRepo, commit := createTestRepo(t, map[string]string{
+		"policy/config.yaml":      "key: value",
+		"policy/lib/helpers.rego": "package lib",
+		"README.md":               "readme",
+	})
+
+	t.Run("subdir", func(t *testing.T) {
+		destination := t.TempDir()
+
+		metadata, err := cloneRepositoryPathInMemory(context.Background(), "policy", destination, &git.CloneOptions{URL: sourceRepo})
+		assert.NoError(t, err)
+
+		content, err := os.ReadFile(filepath.Join(destination, "config.yaml"))
+		assert.NoError(t, err)
+		assert.Equal(t, "key: value", string(content))
+
+		content, err = os.ReadFile(filepath.Join(destination, "lib", "helpers.rego"))
+		assert.NoError(t, err)
+		assert.Equal(t, "package lib", string(content))
+
+		_, err = os.Stat(filepath.Join(destination, "README.md"))
+		assert.True(t, os.IsNotExist(err))
+
+		m, ok := metadata.(*gitMetadata.GitMetadata)
+		assert.True(t, ok)
+		assert.Equal(t, []string{commit.String()}, m.GetHashes())
+	})
+
+	t.Run("single file", func(t *testing.T) {
+		destination := t.TempDir()
+
+		_, err := cloneRepositoryPathInMemory(context.Background(), "README.md", destination, &git.CloneOptions{URL: sourceRepo})
+		assert.NoError(t, err)
+
+		entries, err := os.ReadDir(destination)
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+		assert.Equal(t, "README.md", entries[0].Name())
+	})
+}
+
+func TestProcessUrl_FragmentRef(t *testing.T) {
+	testCases := []struct {
+		name        string
+		rawURL      string
+		expectedSrc string
+		expectedRef string
+	}{
+		{
+			name:        "fragment ref",
+			rawURL:      "github.com/org/repo#v1.0.0",
+			expectedSrc: "https://github.com/org/repo.git",
+			expectedRef: "v1.0.0",
+		},
+		{
+			name:        "ssh scheme",
+			rawURL:      "ssh://git@example.com/org/repo#v1.0.0",
+			expectedSrc: "ssh://git@example.com/org/repo.git",
+			expectedRef: "v1.0.0",
+		},
+		{
+			name:        "ssh scheme with port",
+			rawURL:      "ssh://git@example.com:2222/org/repo",
+			expectedSrc: "ssh://git@example.com:2222/org/repo.git",
+		},
+		{
+			name:        "query ref takes precedence",
+			rawURL:      "github.com/org/repo?ref=main#v1.0.0",
+			expectedSrc: "https://github.com/org/repo.git",
+			expectedRef: "main",
+		},
+		{
+			name:        "fragment ref with subdir",
+			rawURL:      "git::https://github.com/org/repo.git//policy#v1.0.0",
+			expectedSrc: "https://github.com/org/repo.git",
+			expectedRef: "v1.0.0",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			src, ref, _, _, err := processUrl(tc.rawURL)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedSrc, src)
+			assert.Equal(t, tc.expectedRef, ref)
+		})
+	}
+}
+
+// TestGitGatherer_Gather_Offline tests that no clone is attempted when the gatherer is offline
+func TestGitGatherer_Gather_Offline(t *testing.T) {
+	g := &GitGatherer{Offline: true}
+	_, err := g.Gather(context.Background(), "git::https://github.com/org/repo.git", t.TempDir())
+	assert.ErrorIs(t, err, gogather.ErrNetworkDisabled)
+}
+
+func TestGitGatherer_Describe(t *testing.T) {
+	info := (&GitGatherer{}).Describe()
+	assert.Equal(t, "git", info.Name)
+	assert.False(t, info.Extracts)
+	assert.Contains(t, info.Prefixes, "git::")
+	assert.NotEmpty(t, info.Destination)
+}
+
+func TestGitGatherer_Describe_GitHosts(t *testing.T) {
+	assert.NotContains(t, (&GitGatherer{}).Describe().Prefixes, "gitea.internal/")
+
+	original := gogather.GitHosts
+	gogather.GitHosts = append([]string{"gitea.internal"}, gogather.GitHosts...)
+	t.Cleanup(func() { gogather.GitHosts = original })
+
+	info := (&GitGatherer{}).Describe()
+	assert.Contains(t, info.Prefixes, "gitea.internal/")
+	assert.Contains(t, info.Prefixes, "github.com/")
+}
+
+func TestProcessUrl_LocalPath(t *testing.T) {
+	testCases := []struct {
+		rawURL         string
+		expectedSrc    string
+		expectedRef    string
+		expectedSubdir string
+	}{
+		{rawURL: "git::/local/repo", expectedSrc: "/local/repo"},
+		{rawURL: "git:///local/repo", expectedSrc: "/local/repo"},
+		{rawURL: "git::file:///local/repo", expectedSrc: "/local/repo"},
+		{rawURL: "git::/local/repo//policy?ref=main", expectedSrc: "/local/repo", expectedRef: "main", expectedSubdir: "policy"},
+		{rawURL: "git::/local/repo#v1.0.0", expectedSrc: "/local/repo", expectedRef: "v1.0.0"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.rawURL, func(t *testing.T) {
+			src, ref, subdir, _, err := processUrl(tc.rawURL)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedSrc, src)
+			assert.Equal(t, tc.expectedRef, ref)
+			assert.Equal(t, tc.expectedSubdir, subdir)
+		})
+	}
+}
+
+func TestGitGatherer_Gather_LocalRepository(t *testing.T) {
+	repoPath, _ := createTestRepo(t, map[string]string{
+		"README.md":        "readme",
+		"policy/main.rego": "package main",
+	})
+
+	t.Run("repository", func(t *testing.T) {
+		destination := filepath.Join(t.TempDir(), "clone")
+		g := &GitGatherer{}
+		_, err := g.Gather(context.Background(), "git::"+repoPath, destination)
+		assert.NoError(t, err)
+		assert.FileExists(t, filepath.Join(destination, "README.md"))
+	})
+
+	t.Run("subdir", func(t *testing.T) {
+		destination := filepath.Join(t.TempDir(), "policy")
+		g := &GitGatherer{}
+		_, err := g.Gather(context.Background(), "git::"+repoPath+"//policy", destination)
+		assert.NoError(t, err)
+		assert.FileExists(t, filepath.Join(destination, "main.rego"))
+		assert.NoFileExists(t, filepath.Join(destination, "README.md"))
+	})
+}
+
+func TestGitGatherer_Gather_EmptySubdir(t *testing.T) {
+	repoPath, _ := createTestRepo(t, map[string]string{
+		"README.md":        "readme",
+		"policy/main.rego": "package main",
+	})
+	dotGit := filepath.Join(t.TempDir(), "repo.git")
+	assert.NoError(t, os.Rename(repoPath, dotGit))
+
+	for _, strategy := range []CloneStrategy{DiskClone, MemoryClone} {
+		for _, source := range []string{"git::" + dotGit + "//", "git::" + dotGit + "?ref=master//"} {
+			destination := filepath.Join(t.TempDir(), "repo")
+			g := &GitGatherer{Strategy: strategy}
+			_, err := g.Gather(context.Background(), source, destination)
+			assert.NoError(t, err, source)
+			assert.FileExists(t, filepath.Join(destination, "README.md"), source)
+			assert.FileExists(t, filepath.Join(destination, "policy", "main.rego"), source)
+			assert.NoDirExists(t, filepath.Join(destination, ".git"), source)
+		}
+	}
+
+	for _, source := range []string{"github.com/org/repo//", "https://github.com/org/repo.git//"} {
+		u, err := ParseGitURL(source)
+		assert.NoError(t, err)
+		assert.Equal(t, "https://github.com/org/repo.git", u.Repo)
+		assert.Equal(t, ".", u.Subdir)
+	}
+}
+
+func TestValidateSubdir(t *testing.T) {
+	for _, subdir := range []string{"", "policy", "policy/lib", "policy/..lib", "policy/lib.."} {
+		assert.NoError(t, validateSubdir(subdir), subdir)
+	}
+	for _, subdir := range []string{"..", "../../etc", "policy/../../etc", `policy\..\..\etc`, "/etc", `\etc`} {
+		assert.ErrorIs(t, validateSubdir(subdir), ErrInvalidSubdir, subdir)
+	}
+}
+
+func TestGitGatherer_Gather_InvalidSubdir(t *testing.T) {
+	repoPath, _ := createTestRepo(t, map[string]string{"README.md": "readme"})
+
+	for _, source := range []string{"git::" + repoPath + "//../../etc", "git::" + repoPath + "///etc"} {
+		t.Run(source, func(t *testing.T) {
+			destination := filepath.Join(t.TempDir(), "out")
+			g := &GitGatherer{}
+			_, err := g.Gather(context.Background(), source, destination)
+			assert.ErrorIs(t, err, ErrInvalidSubdir)
+			assert.NoDirExists(t, destination)
+		})
+	}
+}
+
+func TestGitGatherer_Gather_CloneTimeout(t *testing.T) {
+	// A server that never responds, until the client gives up
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	for name, source := range map[string]string{"repository": "git::" + server.URL + "/repo.git", "subdir": "git::" + server.URL + "/repo.git//policy"} {
+		t.Run(name, func(t *testing.T) {
+			g := &GitGatherer{CloneTimeout: 100 * time.Millisecond}
+
+			start := time.Now()
+			_, err := g.Gather(context.Background(), source, filepath.Join(t.TempDir(), "out"))
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+			assert.Less(t, time.Since(start), 5*time.Second)
+		})
+	}
+}
+
+// TestGitGatherer_ResolveRef tests resolving branches and tags of a repository without cloning it
+func TestGitGatherer_ResolveRef(t *testing.T) {
+	dir, commit := createTestRepo(t, map[string]string{"file.txt": "content"})
+	r, err := git.PlainOpen(dir)
+	assert.NoError(t, err)
+	head, err := r.Head()
+	assert.NoError(t, err)
+
+	_, err = r.CreateTag("v1.0.0", commit, &git.CreateTagOptions{
+		Message: "Release",
+		Tagger:  &object.Signature{Name: "Test User", Email: "test@example.com", When: time.Now()},
+	})
+	assert.NoError(t, err)
+	_, err = r.CreateTag("lightweight", commit, nil)
+	assert.NoError(t, err)
+
+	g := &GitGatherer{}
+	for _, ref := range []string{head.Name().Short(), head.Name().String(), "v1.0.0", "lightweight"} {
+		t.Run(ref, func(t *testing.T) {
+			hash, err := g.ResolveRef(context.Background(), "git::"+dir, ref)
+			assert.NoError(t, err)
+			assert.Equal(t, commit.String(), hash)
+		})
+	}
+
+	t.Run("missing", func(t *testing.T) {
+		_, err := g.ResolveRef(context.Background(), "git::"+dir, "missing")
+		assert.ErrorIs(t, err, ErrRefNotFound)
+	})
+
+	t.Run("offline", func(t *testing.T) {
+		_, err := (&GitGatherer{Offline: true}).ResolveRef(context.Background(), "git::"+dir, "v1.0.0")
+		assert.ErrorIs(t, err, gogather.ErrNetworkDisabled)
+	})
+}
+
+// TestGitGatherer_Head tests resolving the commit of a repository without cloning it
+func TestGitGatherer_Head(t *testing.T) {
+	dir, commit := createTestRepo(t, map[string]string{"file.txt": "content"})
+	r, err := git.PlainOpen(dir)
+	assert.NoError(t, err)
+	_, err = r.CreateTag("v1.0.0", commit, nil)
+	assert.NoError(t, err)
+
+	for _, source := range []string{"git::" + dir, "git::" + dir + "?ref=v1.0.0"} {
+		m, err := (&GitGatherer{}).Head(context.Background(), source)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{commit.String()}, m.(*gitMetadata.GitMetadata).GetHashes())
+	}
+}
+
+// TestGitGatherer_Gather_TagFragment tests cloning the tag named by the fragment of the source
+func TestGitGatherer_Gather_TagFragment(t *testing.T) {
+	dir, commit := createTestRepo(t, map[string]string{"file.txt": "tagged"})
+	r, err := git.PlainOpen(dir)
+	assert.NoError(t, err)
+	_, err = r.CreateTag("v1.0.0", commit, nil)
+	assert.NoError(t, err)
+
+	// Move the branch past the tag, so that cloning the branch instead would be noticed
+	w, err := r.Worktree()
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "file.txt"), []byte("changed"), 0600))
+	_, err = w.Add("file.txt")
+	assert.NoError(t, err)
+	_, err = w.Commit("Second commit", &git.CommitOptions{
+		Author: &object.Signature{Name: "Test User", Email: "test@example.com", When: time.Now()},
+	})
+	assert.NoError(t, err)
+
+	for _, tc := range []struct{ source, file string }{
+		{source: "git::" + dir + "#v1.0.0", file: "file.txt"},
+		{source: "git::" + dir + "//file.txt#v1.0.0"},
+		{source: "git::" + dir + "?ref=v1.0.0", file: "file.txt"},
+	} {
+		destination := filepath.Join(t.TempDir(), "repo")
+		m, err := (&GitGatherer{}).Gather(context.Background(), tc.source, destination)
+		assert.NoError(t, err, tc.source)
+		assert.Contains(t, m.(*gitMetadata.GitMetadata).GetHashes(), commit.String())
+		assert.Equal(t, "refs/tags/v1.0.0", m.(*gitMetadata.GitMetadata).Ref)
+
+		content, err := os.ReadFile(filepath.Join(destination, tc.file))
+		assert.NoError(t, err)
+		assert.Equal(t, "tagged", string(content))
+
+		// Gathering into the clone again checks the tag out again
+		if tc.file != "" {
+			assert.NoError(t, os.WriteFile(filepath.Join(destination, tc.file), []byte("local"), 0600))
+			_, err = (&GitGatherer{}).Gather(context.Background(), tc.source, destination)
+			assert.NoError(t, err)
+			content, err = os.ReadFile(filepath.Join(destination, tc.file))
+			assert.NoError(t, err)
+			assert.Equal(t, "tagged", string(content))
+		}
+	}
+}
+
+// TestGitGatherer_Gather_RefFallback tests cloning the default branch when the ref of the source is not found
+func TestGitGatherer_Gather_RefFallback(t *testing.T) {
+	dir, commit := createTestRepo(t, map[string]string{"file.txt": "content"})
+	source := "git::" + dir + "?ref=missing"
+
+	_, err := (&GitGatherer{}).Gather(context.Background(), source, filepath.Join(t.TempDir(), "repo"))
+	assert.ErrorIs(t, err, plumbing.ErrReferenceNotFound)
+
+	for _, subdir := range []string{"", "//file.txt"} {
+		destination := filepath.Join(t.TempDir(), "repo")
+		source := "git::" + dir + subdir + "?ref=missing"
+		m, err := (&GitGatherer{RefFallback: true}).Gather(context.Background(), source, destination)
+		assert.NoError(t, err)
+		assert.Contains(t, m.(*gitMetadata.GitMetadata).GetHashes(), commit.String())
+		assert.Equal(t, "refs/heads/master", m.(*gitMetadata.GitMetadata).Ref)
+	}
+}
+
+// TestGitGatherer_Gather_ExistingClone tests that gathering into an existing clone fetches and checks out new commits
+func TestGitGatherer_Gather_ExistingClone(t *testing.T) {
+	dir, _ := createTestRepo(t, map[string]string{"file.txt": "content"})
+	destination := filepath.Join(t.TempDir(), "repo")
+
+	_, err := (&GitGatherer{}).Gather(context.Background(), "git::"+dir, destination)
+	assert.NoError(t, err)
+
+	// Add a commit to the source repository
+	r, err := git.PlainOpen(dir)
+	assert.NoError(t, err)
+	w, err := r.Worktree()
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "new.txt"), []byte("new"), 0600))
+	_, err = w.Add("new.txt")
+	assert.NoError(t, err)
+	commit, err := w.Commit("Second commit", &git.CommitOptions{
+		Author: &object.Signature{Name: "Test User", Email: "test@example.com", When: time.Now()},
+	})
+	assert.NoError(t, err)
+
+	m, err := (&GitGatherer{}).Gather(context.Background(), "git::"+dir, destination)
+	assert.NoError(t, err)
+	assert.Contains(t, m.(*gitMetadata.GitMetadata).GetHashes(), commit.String())
+
+	clone, err := git.PlainOpen(destination)
+	assert.NoError(t, err)
+	head, err := clone.Head()
+	assert.NoError(t, err)
+	assert.Equal(t, commit, head.Hash())
+	assert.FileExists(t, filepath.Join(destination, "new.txt"))
+
+	// A clone of another repository is not updated
+	other, _ := createTestRepo(t, map[string]string{"file.txt": "other"})
+	_, err = (&GitGatherer{}).Gather(context.Background(), "git::"+other, destination)
+	assert.ErrorIs(t, err, git.ErrRepositoryAlreadyExists)
+}
+
+// TestGitGatherer_Gather_MaxCloneBytes tests that a repository larger than MaxCloneBytes is not gathered
+func TestGitGatherer_Gather_MaxCloneBytes(t *testing.T) {
+	dir, _ := createTestRepo(t, map[string]string{"file.txt": string(make([]byte, 8192))})
+
+	destination := filepath.Join(t.TempDir(), "repo")
+	_, err := (&GitGatherer{MaxCloneBytes: 1024}).Gather(context.Background(), "git::"+dir, destination)
+	assert.ErrorIs(t, err, ErrCloneTooLarge)
+	assert.NoDirExists(t, destination)
+
+	_, err = (&GitGatherer{MaxCloneBytes: 1 << 20}).Gather(context.Background(), "git::"+dir, destination)
+	assert.NoError(t, err)
+	assert.FileExists(t, filepath.Join(destination, "file.txt"))
+}
+
+// TestGitGatherer_Gather_CredentialHelper tests that an https source is cloned with the credentials of the git credential helper
+func TestGitGatherer_Gather_CredentialHelper(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	source := "git::" + server.URL + "/org/repo.git"
+
+	// Without a helper, the source is cloned without credentials
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(t.TempDir(), "gitconfig"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	_, err := (&GitGatherer{CredentialHelper: true}).Gather(context.Background(), source, filepath.Join(t.TempDir(), "repo"))
+	assert.Error(t, err)
+	assert.Empty(t, authorization)
+
+	helper := filepath.Join(t.TempDir(), "helper.sh")
+	assert.NoError(t, os.WriteFile(helper, []byte("#!/bin/sh\necho username=user\necho password=secret\n"), 0700))
+	config := filepath.Join(t.TempDir(), "gitconfig")
+	assert.NoError(t, os.WriteFile(config, []byte("[credential]\n\thelper = "+helper+"\n"), 0600))
+	t.Setenv("GIT_CONFIG_GLOBAL", config)
+
+	_, err = (&GitGatherer{CredentialHelper: true}).Gather(context.Background(), source, filepath.Join(t.TempDir(), "repo"))
+	assert.Error(t, err)
+	assert.Equal(t, "Basic dXNlcjpzZWNyZXQ=", authorization)
+
+	// The helper is only asked when CredentialHelper is set
+	authorization = ""
+	_, err = (&GitGatherer{}).Gather(context.Background(), source, filepath.Join(t.TempDir(), "repo"))
+	assert.Error(t, err)
+	assert.Empty(t, authorization)
+}
+
+func TestParseGitURL(t *testing.T) {
+	testCases := []struct {
+		name     string
+		rawURL   string
+		expected GitURL
+	}{
+		{
+			name:     "scp",
+			rawURL:   "git@github.com:org/repo.git",
+			expected: GitURL{Repo: "ssh://git@github.com/org/repo.git", Scheme: "ssh", Auth: "ssh"},
+		},
+		{
+			name:     "https",
+			rawURL:   "https://github.com/org/repo",
+			expected: GitURL{Repo: "https://github.com/org/repo.git", Scheme: "https"},
+		},
+		{
+			name:     "git:: prefix",
+			rawURL:   "git::https://github.com/org/repo.git",
+			expected: GitURL{Repo: "https://github.com/org/repo.git", Scheme: "https"},
+		},
+		{
+			name:     "subdir",
+			rawURL:   "git::git@github.com:org/repo.git//policy/lib",
+			expected: GitURL{Repo: "ssh://git@github.com/org/repo.git", Subdir: "policy/lib", Scheme: "ssh", Auth: "ssh"},
+		},
+		{
+			name:     "ref",
+			rawURL:   "github.com/org/repo?ref=main",
+			expected: GitURL{Repo: "https://github.com/org/repo.git", Ref: "main", Scheme: "https"},
+		},
+		{
+			name:     "depth",
+			rawURL:   "git::https://github.com/org/repo.git//policy?ref=v1&depth=1",
+			expected: GitURL{Repo: "https://github.com/org/repo.git", Ref: "v1", Subdir: "policy", Depth: "1", Scheme: "https"},
+		},
+		{
+			name:     "fragment",
+			rawURL:   "github.com/org/repo#v1.0.0",
+			expected: GitURL{Repo: "https://github.com/org/repo.git", Ref: "v1.0.0", Scheme: "https"},
+		},
+		{
+			name:     "local path",
+			rawURL:   "git::/local/repo//policy?ref=main",
+			expected: GitURL{Repo: "/local/repo", Ref: "main", Subdir: "policy", Scheme: "file"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			u, err := ParseGitURL(tc.rawURL)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, u)
+		})
+	}
+
+	_, err := ParseGitURL("basic.git")
+	assert.Error(t, err)
+}
+
+func TestParseGitURL_PercentEncoded(t *testing.T) {
+	testCases := []struct {
+		rawURL         string
+		expectedRef    string
+		expectedSubdir string
+	}{
+		{rawURL: "git::https://github.com/org/repo.git//my%20policy?ref=feature%2Fx", expectedRef: "feature/x", expectedSubdir: "my policy"},
+		{rawURL: "github.com/org/repo//policy%2Flib#feature%2Fy", expectedRef: "feature/y", expectedSubdir: "policy/lib"},
+		{rawURL: "git::https://github.com/org/repo.git?ref=main%2F%2Fmy%20policy", expectedRef: "main", expectedSubdir: "my policy"},
+		{rawURL: "git::git@github.com:org/repo.git//my%20policy?ref=feature%2Fx", expectedRef: "feature/x", expectedSubdir: "my policy"},
+		{rawURL: "git::/local/repo//my%20policy?ref=feature%2Fx", expectedRef: "feature/x", expectedSubdir: "my policy"},
+	}
+
+	for _, tc := range testCases {
+		u, err := ParseGitURL(tc.rawURL)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expectedRef, u.Ref, tc.rawURL)
+		assert.Equal(t, tc.expectedSubdir, u.Subdir, tc.rawURL)
+	}
+}
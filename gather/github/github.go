@@ -0,0 +1,198 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package github provides functionality for gathering the assets of GitHub releases.
+// It includes a GitHubGatherer struct that implements the Gatherer interface,
+// resolving the asset with the GitHub API and downloading it with the HTTP gatherer.
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	gogather "github.com/enterprise-contract/go-gather"
+	httpGatherer "github.com/enterprise-contract/go-gather/gather/http"
+	"github.com/enterprise-contract/go-gather/metadata"
+)
+
+// DefaultAPIURL is the URL of the GitHub API used when APIURL is not set.
+const DefaultAPIURL = "https://api.github.com"
+
+var (
+	// ErrInvalidSource is returned when the source is not of the form "github://owner/repo/tag/asset".
+	ErrInvalidSource = errors.New("invalid GitHub release asset source")
+	// ErrAssetNotFound is returned when the release has no asset with the requested name.
+	ErrAssetNotFound = errors.New("release asset not found")
+)
+
+// GitHubGatherer is a struct that implements the Gatherer interface
+// and provides methods for gathering the assets of GitHub releases.
+type GitHubGatherer struct {
+	// APIURL is the URL of the GitHub API, e.g. that of a GitHub Enterprise Server. If empty, DefaultAPIURL is used.
+	APIURL string
+	// Token authenticates the requests to the GitHub API. If empty, the GITHUB_TOKEN environment variable is used.
+	Token string
+	// Offline makes Gather fail immediately with gogather.ErrNetworkDisabled.
+	Offline bool
+}
+
+// release is the part of a GitHub release returned by the GitHub API that is used to find its assets
+type release struct {
+	Assets []asset `json:"assets"`
+}
+
+// asset is a GitHub release asset, with the API URL it is downloaded from
+type asset struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// Describe returns information about the sources handled by the GitHubGatherer and how it writes them.
+func (g *GitHubGatherer) Describe() gogather.GathererInfo {
+	return gogather.GathererInfo{
+		Name:        "github",
+		Prefixes:    []string{"github://"},
+		Destination: "the asset is written into the destination directory if it ends with a separator or has no extension, otherwise to the destination path",
+	}
+}
+
+// Gather downloads the release asset named by source, of the form "github://owner/repo/tag/asset",
+// to the destination. The tag "latest" refers to the latest release of the repository.
+func (g *GitHubGatherer) Gather(ctx context.Context, source, destination string) (metadata.Metadata, error) {
+	if g.Offline {
+		return nil, fmt.Errorf("%w: cannot gather %s", gogather.ErrNetworkDisabled, source)
+	}
+
+	owner, repo, tag, name, err := parseSource(source)
+	if err != nil {
+		return nil, err
+	}
+
+	apiURL, err := url.Parse(g.apiURL())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse API URL: %w", err)
+	}
+
+	h := httpGatherer.NewHTTPGatherer()
+	h.Client.Transport = &apiTransport{host: apiURL.Host, token: g.token(), base: http.DefaultTransport}
+
+	a, err := findAsset(ctx, &h.Client, g.apiURL(), owner, repo, tag, name)
+	if err != nil {
+		return nil, err
+	}
+
+	// The asset URL is named by its ID, so name the downloaded file after the asset
+	h.Filename = a.Name
+	return h.Gather(ctx, a.URL, destination)
+}
+
+// apiURL returns the URL of the GitHub API without a trailing slash
+func (g *GitHubGatherer) apiURL() string {
+	if g.APIURL == "" {
+		return DefaultAPIURL
+	}
+	return strings.TrimSuffix(g.APIURL, "/")
+}
+
+// token returns the token used to authenticate the requests to the GitHub API, if any
+func (g *GitHubGatherer) token() string {
+	if g.Token != "" {
+		return g.Token
+	}
+	return os.Getenv("GITHUB_TOKEN")
+}
+
+// parseSource splits a source of the form "github://owner/repo/tag/asset" into its parts
+func parseSource(source string) (owner, repo, tag, name string, err error) {
+	parts := strings.Split(strings.TrimPrefix(source, "github://"), "/")
+	if !strings.HasPrefix(source, "github://") || len(parts) != 4 {
+		return "", "", "", "", fmt.Errorf("%w: expected github://owner/repo/tag/asset, got %s", ErrInvalidSource, source)
+	}
+	for _, part := range parts {
+		if part == "" {
+			return "", "", "", "", fmt.Errorf("%w: expected github://owner/repo/tag/asset, got %s", ErrInvalidSource, source)
+		}
+	}
+	return parts[0], parts[1], parts[2], parts[3], nil
+}
+
+// findAsset returns the asset with the given name of the release with the tag, or of the latest release if the tag is "latest"
+func findAsset(ctx context.Context, client *http.Client, apiURL, owner, repo, tag, name string) (asset, error) {
+	releaseURL := fmt.Sprintf("%s/repos/%s/%s/releases/tags/%s", apiURL, url.PathEscape(owner), url.PathEscape(repo), url.PathEscape(tag))
+	if tag == "latest" {
+		releaseURL = fmt.Sprintf("%s/repos/%s/%s/releases/latest", apiURL, url.PathEscape(owner), url.PathEscape(repo))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, releaseURL, nil)
+	if err != nil {
+		return asset{}, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", "Go-Gather")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return asset{}, fmt.Errorf("error getting release %s of %s/%s: %w", tag, owner, repo, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return asset{}, fmt.Errorf("error getting release %s of %s/%s: %w", tag, owner, repo, &httpGatherer.HTTPStatusError{StatusCode: resp.StatusCode, Status: resp.Status})
+	}
+
+	var r release
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return asset{}, fmt.Errorf("error parsing release %s of %s/%s: %w", tag, owner, repo, err)
+	}
+
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a, nil
+		}
+	}
+	return asset{}, fmt.Errorf("%w: %s in release %s of %s/%s", ErrAssetNotFound, name, tag, owner, repo)
+}
+
+// apiTransport authenticates the requests to the GitHub API host with the token, and asks for the content of
+// release assets rather than their description. Requests to other hosts, such as the storage release assets are
+// redirected to, are sent as is.
+type apiTransport struct {
+	host  string
+	token string
+	base  http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *apiTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != t.host {
+		return t.base.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/octet-stream")
+	}
+	if t.token != "" {
+		req.Header.Set("Authorization", "Bearer "+t.token)
+	}
+	return t.base.RoundTrip(req)
+}
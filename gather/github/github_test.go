@@ -0,0 +1,125 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gogather "github.com/enterprise-contract/go-gather"
+	httpGatherer "github.com/enterprise-contract/go-gather/gather/http"
+)
+
+// newTestAPI starts a server mocking the GitHub API for the releases v1.0.0 and latest of org/repo,
+// each with a "policy.tar.gz" asset. Asset downloads are redirected to a storage server, as GitHub does.
+func newTestAPI(t *testing.T, token string) string {
+	t.Helper()
+
+	storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("expected no Authorization header to be sent to the storage")
+		}
+		_, _ = w.Write([]byte("policy content"))
+	}))
+	t.Cleanup(storage.Close)
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token != "" && r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/repos/org/repo/releases/tags/v1.0.0", "/repos/org/repo/releases/latest":
+			if r.Header.Get("Accept") != "application/vnd.github+json" {
+				t.Errorf("unexpected Accept header for the release: %s", r.Header.Get("Accept"))
+			}
+			fmt.Fprintf(w, `{"assets": [{"name": "other.txt", "url": "%[1]s/repos/org/repo/releases/assets/1"}, {"name": "policy.tar.gz", "url": "%[1]s/repos/org/repo/releases/assets/2"}]}`, server.URL)
+		case "/repos/org/repo/releases/assets/2":
+			if r.Header.Get("Accept") != "application/octet-stream" {
+				t.Errorf("unexpected Accept header for the asset: %s", r.Header.Get("Accept"))
+			}
+			http.Redirect(w, r, storage.URL+"/policy.tar.gz", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+// TestGitHubGatherer_Gather tests downloading release assets resolved with the GitHub API.
+func TestGitHubGatherer_Gather(t *testing.T) {
+	api := newTestAPI(t, "secret")
+	t.Setenv("GITHUB_TOKEN", "secret")
+
+	for _, tag := range []string{"v1.0.0", "latest"} {
+		t.Run(tag, func(t *testing.T) {
+			dir := t.TempDir()
+			g := &GitHubGatherer{APIURL: api}
+			if _, err := g.Gather(context.Background(), "github://org/repo/"+tag+"/policy.tar.gz", dir+"/"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			content, err := os.ReadFile(filepath.Join(dir, "policy.tar.gz"))
+			if err != nil || string(content) != "policy content" {
+				t.Errorf("expected the asset to be downloaded, but got: %q, %v", content, err)
+			}
+		})
+	}
+}
+
+// TestGitHubGatherer_Gather_Errors tests the errors returned for sources that cannot be gathered.
+func TestGitHubGatherer_Gather_Errors(t *testing.T) {
+	api := newTestAPI(t, "secret")
+
+	tests := []struct {
+		name     string
+		gatherer *GitHubGatherer
+		source   string
+		err      error
+	}{
+		{name: "missing asset", gatherer: &GitHubGatherer{APIURL: api, Token: "secret"}, source: "github://org/repo/v1.0.0/missing.txt", err: ErrAssetNotFound},
+		{name: "invalid source", gatherer: &GitHubGatherer{APIURL: api, Token: "secret"}, source: "github://org/repo/policy.tar.gz", err: ErrInvalidSource},
+		{name: "offline", gatherer: &GitHubGatherer{APIURL: api, Offline: true}, source: "github://org/repo/v1.0.0/policy.tar.gz", err: gogather.ErrNetworkDisabled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.gatherer.Gather(context.Background(), tt.source, t.TempDir()+"/")
+			if !errors.Is(err, tt.err) {
+				t.Errorf("expected error to wrap %v, but got: %v", tt.err, err)
+			}
+		})
+	}
+
+	t.Run("unauthorized", func(t *testing.T) {
+		t.Setenv("GITHUB_TOKEN", "")
+		_, err := (&GitHubGatherer{APIURL: api}).Gather(context.Background(), "github://org/repo/v1.0.0/policy.tar.gz", t.TempDir()+"/")
+		var statusErr *httpGatherer.HTTPStatusError
+		if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusUnauthorized {
+			t.Errorf("expected a 401 HTTPStatusError, but got: %v", err)
+		}
+	})
+}
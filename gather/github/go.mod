@@ -0,0 +1,15 @@
+module github.com/enterprise-contract/go-gather/gather/github
+
+go 1.21.9
+
+require (
+	github.com/enterprise-contract/go-gather v0.0.2
+	github.com/enterprise-contract/go-gather/gather/http v0.0.1
+	github.com/enterprise-contract/go-gather/metadata v0.0.2
+)
+
+require (
+	github.com/enterprise-contract/go-gather/metadata/http v0.0.1 // indirect
+	github.com/enterprise-contract/go-gather/saver v0.0.1 // indirect
+	github.com/enterprise-contract/go-gather/saver/file v0.0.1 // indirect
+)
@@ -4,11 +4,13 @@ go 1.21.9
 
 require (
 	github.com/enterprise-contract/go-gather v0.0.2
+	github.com/enterprise-contract/go-gather/expander v0.0.1
 	github.com/enterprise-contract/go-gather/gather/file v0.0.1
 	github.com/enterprise-contract/go-gather/gather/git v0.0.2
 	github.com/enterprise-contract/go-gather/gather/http v0.0.1
 	github.com/enterprise-contract/go-gather/gather/oci v0.0.2
 	github.com/enterprise-contract/go-gather/metadata v0.0.2
+	github.com/enterprise-contract/go-gather/metadata/file v0.0.1
 	github.com/enterprise-contract/go-gather/metadata/git v0.0.1
 )
 
@@ -19,8 +21,6 @@ require (
 	github.com/cloudflare/circl v1.3.9 // indirect
 	github.com/cyphar/filepath-securejoin v0.2.5 // indirect
 	github.com/emirpasic/gods v1.18.1 // indirect
-	github.com/enterprise-contract/go-gather/expander v0.0.1 // indirect
-	github.com/enterprise-contract/go-gather/metadata/file v0.0.1 // indirect
 	github.com/enterprise-contract/go-gather/metadata/http v0.0.1 // indirect
 	github.com/enterprise-contract/go-gather/metadata/oci v0.0.1 // indirect
 	github.com/enterprise-contract/go-gather/saver v0.0.1 // indirect
@@ -0,0 +1,107 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	gogather "github.com/enterprise-contract/go-gather"
+	"github.com/enterprise-contract/go-gather/metadata"
+	httpMetadata "github.com/enterprise-contract/go-gather/metadata/http"
+)
+
+// gatherChecksum gathers source like download, but copies it from the Cache if it holds the Checksum,
+// and verifies the Checksum of a downloaded file before adding it to the Cache. The metadata of a file
+// copied from the Cache has no status code or headers.
+func (h *HTTPGatherer) gatherChecksum(ctx context.Context, source, destination, sourceFileName string, head *http.Response) (metadata.Metadata, error) {
+	expected, ok := strings.CutPrefix(h.Checksum, "sha256:")
+	if !ok {
+		return nil, fmt.Errorf("unsupported checksum %q, expected a sha256 digest", h.Checksum)
+	}
+
+	if h.Cache != nil && h.FS == nil {
+		path := localPath(destination)
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			destination = filepath.Join(destination, sourceFileName)
+			path = filepath.Join(path, sourceFileName)
+		}
+
+		hit, err := h.Cache.Get(h.Checksum, path)
+		if err != nil {
+			return nil, err
+		}
+		if hit {
+			info, err := os.Stat(path)
+			if err != nil {
+				return nil, fmt.Errorf("error reading cached file: %w", err)
+			}
+			return httpMetadata.HTTPMetadata{ContentLength: info.Size(), Destination: destination}, nil
+		}
+	}
+
+	m, err := h.download(ctx, source, destination, sourceFileName, head)
+	if err != nil {
+		return nil, err
+	}
+
+	saved := m.(httpMetadata.HTTPMetadata).Destination
+	actual, err := h.fileSHA256(saved)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(actual, expected) {
+		_ = h.remove(saved)
+		return nil, fmt.Errorf("%w: expected sha256:%s from %s, got sha256:%s", ErrChecksumMismatch, expected, source, actual)
+	}
+
+	if h.Cache != nil && h.FS == nil {
+		if err := h.Cache.Put(h.Checksum, localPath(saved)); err != nil {
+			return nil, fmt.Errorf("error caching file: %w", err)
+		}
+	}
+	return m, nil
+}
+
+// localPath returns the path of the file destination on the local filesystem
+func localPath(destination string) string {
+	destination = strings.TrimPrefix(destination, "file::")
+	destination = strings.TrimPrefix(destination, "file://")
+	return gogather.ExpandTilde(destination)
+}
+
+// fileSHA256 returns the hex encoded SHA256 digest of the saved file at the destination
+func (h *HTTPGatherer) fileSHA256(destination string) (string, error) {
+	f, err := h.open(destination)
+	if err != nil {
+		return "", fmt.Errorf("error reading downloaded file: %w", err)
+	}
+	defer f.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return "", fmt.Errorf("error reading downloaded file: %w", err)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
@@ -36,10 +36,17 @@
 package http
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"mime"
 	"net/http"
+	"net/http/cookiejar"
 	"net/url"
+	"os"
 	"path/filepath"
 	"strings"
 	"time"
@@ -50,8 +57,103 @@ import (
 	"github.com/enterprise-contract/go-gather/saver"
 )
 
+var (
+	// ErrHTTPStatus is returned when the server responds with a status code other than 200 OK.
+	ErrHTTPStatus = errors.New("response code error")
+	// ErrRedirectNotFollowed is returned when the server responds with a redirect that MaxRedirects does not allow to be followed.
+	ErrRedirectNotFollowed = errors.New("redirect not followed")
+	// ErrUnexpectedContentType is returned when the Content-Type of a response does not match ExpectedContentType.
+	ErrUnexpectedContentType = errors.New("unexpected content type")
+	// ErrChecksumMismatch is returned when the digest of a downloaded file does not match Checksum.
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	// ErrContentLengthMismatch is returned when fewer bytes are received than the Content-Length of the response.
+	ErrContentLengthMismatch = errors.New("content length mismatch")
+	// ErrInsufficientDiskSpace is returned when the Content-Length of a response exceeds the free space at the destination.
+	ErrInsufficientDiskSpace = errors.New("insufficient disk space")
+	// ErrInvalidSource is returned by Validate when the source is not an absolute http or https URL.
+	ErrInvalidSource = errors.New("invalid HTTP source")
+	// ErrEmptyResponse is returned when the response has an empty body and AllowEmpty is false.
+	ErrEmptyResponse = errors.New("empty response")
+	// ErrSignatureMismatch is returned when a downloaded file has no valid detached signature by the SignatureKey.
+	ErrSignatureMismatch = errors.New("signature verification failed")
+
+	// errDiskFreeUnsupported is returned by diskFree on platforms where the free space cannot be determined.
+	errDiskFreeUnsupported = errors.New("determining free disk space is not supported on this platform")
+)
+
+// availableSpace returns the number of bytes available on the filesystem containing path.
+var availableSpace = diskFree
+
+// HTTPStatusError is returned when the server responds with a status code other than 200 OK.
+// It wraps ErrHTTPStatus, and can be retrieved with errors.As to inspect the status code.
+type HTTPStatusError struct {
+	// StatusCode is the status code of the response, e.g. 404.
+	StatusCode int
+	// Status is the status text of the response, e.g. "404 Not Found".
+	Status string
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("%s: %d", ErrHTTPStatus, e.StatusCode)
+}
+
+func (e *HTTPStatusError) Unwrap() error {
+	return ErrHTTPStatus
+}
+
 type HTTPGatherer struct {
 	Client http.Client
+	// CheckDiskSpace makes Gather compare the Content-Length of the response with the space available
+	// at the destination, failing with ErrInsufficientDiskSpace before downloading if it will not fit.
+	CheckDiskSpace bool
+	// ExpectedContentType is the media type, e.g. "application/gzip", the response must have.
+	// Parameters such as charset are ignored. If empty, any content type is accepted.
+	ExpectedContentType string
+	// Cookies are sent with the requests to the host of the source, e.g. to provide a session cookie.
+	// They are added to the Jar of the Client, if it has one.
+	Cookies []*http.Cookie
+	// Filename is the name of the file written when the destination is a directory.
+	// If empty, the name of the file in the source URL is used.
+	Filename string
+	// MaxRedirects is the maximum number of redirects followed, with zero disabling them.
+	// A redirect that is not followed fails with ErrRedirectNotFollowed. If nil, the Client's redirect policy is used.
+	MaxRedirects *int
+	// Offline makes Gather fail immediately with gogather.ErrNetworkDisabled.
+	Offline bool
+	// Parallelism is the number of byte ranges downloaded concurrently into a file destination, if the
+	// server accepts range requests and reports the Content-Length in response to a HEAD request.
+	// Otherwise, including if the HEAD request fails or a range request is answered with the whole
+	// file, or if it is less than two, the file is downloaded in a single request.
+	Parallelism int
+	// Checksum is the digest, e.g. "sha256:<hex>", the downloaded file must have. A file with another
+	// digest is removed, and Gather fails with ErrChecksumMismatch. Only sha256 digests are supported.
+	Checksum string
+	// Cache holds files by their Checksum. If both are set, a file that is cached is copied from the Cache
+	// rather than downloaded, and a downloaded file is added to it.
+	Cache gogather.Cache
+	// Prefetch makes Gather send a HEAD request before downloading, failing if the resource does not exist.
+	// If the response has a Content-Disposition filename, it is used in place of the name in the source URL.
+	Prefetch bool
+	// Method is the method of the request the content is downloaded with, e.g. "POST" for a source that returns
+	// it in response to a request body. If empty, GET is used. Only GET downloads are split into byte ranges.
+	Method string
+	// Body is sent as the body of the request the content is downloaded with.
+	Body []byte
+	// AllowEmpty determines whether a response with an empty body is saved as an empty file. If false, the
+	// file is removed and Gather fails with ErrEmptyResponse. If nil, empty responses are allowed.
+	AllowEmpty *bool
+	// Mirrors are URLs the file is downloaded from, in order, if it cannot be downloaded from the source because
+	// the request fails or the server responds with an error status. The URL the file was downloaded from is
+	// recorded in the FinalURI of the metadata.
+	Mirrors []string
+	// SignatureKey is an OpenPGP public key, armored or binary, that the downloaded file must have a valid detached
+	// signature by. The signature is downloaded from the URL of the file with SignatureSuffix appended, or ".asc"
+	// if it is empty. A file without a valid signature is removed, and Gather fails with ErrSignatureMismatch.
+	SignatureKey    []byte
+	SignatureSuffix string
+	// FS is the filesystem the file is saved to in place of the local filesystem, e.g. a saver.MemoryFS to gather
+	// into memory. The file is downloaded in a single request, and CheckDiskSpace and the Cache are not used.
+	FS saver.FS
 }
 
 func NewHTTPGatherer() *HTTPGatherer {
@@ -62,8 +164,40 @@ func NewHTTPGatherer() *HTTPGatherer {
 	}
 }
 
+// Describe returns information about the sources handled by the HTTPGatherer and how it writes them.
+func (h *HTTPGatherer) Describe() gogather.GathererInfo {
+	return gogather.GathererInfo{
+		Name:        "http",
+		Prefixes:    []string{"http::", "http://", "https://"},
+		Extracts:    false,
+		Destination: "the file is written into the destination directory if it ends with a separator or has no extension, otherwise to the destination path",
+	}
+}
+
 func (h *HTTPGatherer) Gather(ctx context.Context, source, destination string) (metadata.Metadata, error) {
+	if h.Offline {
+		return nil, fmt.Errorf("%w: cannot gather %s", gogather.ErrNetworkDisabled, source)
+	}
+
+	if h.FS == nil {
+		if err := gogather.ValidateDestination(destination); err != nil {
+			return nil, err
+		}
+	}
 
+	m, err := h.gather(ctx, source, destination)
+	for _, mirror := range h.Mirrors {
+		var urlErr *url.Error
+		if err == nil || (!errors.Is(err, ErrHTTPStatus) && !errors.As(err, &urlErr)) {
+			break
+		}
+		m, err = h.gather(ctx, mirror, destination)
+	}
+	return m, err
+}
+
+// gather downloads the file at source to the destination.
+func (h *HTTPGatherer) gather(ctx context.Context, source, destination string) (metadata.Metadata, error) {
 	// Parse source
 	src, err := url.Parse(source)
 	if err != nil {
@@ -75,8 +209,23 @@ func (h *HTTPGatherer) Gather(ctx context.Context, source, destination string) (
 		return nil, fmt.Errorf("no source scheme provided")
 	}
 
-	// Get the source filename
+	// Get the source filename, unless it is overridden
 	sourceFileName := filepath.Base(src.Path)
+	var head *http.Response
+	if h.Prefetch {
+		if head, err = h.head(ctx, source); err != nil {
+			return nil, err
+		}
+		if name := dispositionFilename(head); name != "" {
+			sourceFileName = name
+		}
+	} else if h.Parallelism > 1 && (h.Method == "" || h.Method == http.MethodGet) && h.FS == nil {
+		// A server that rejects HEAD requests is downloaded from in a single request
+		head, _ = h.head(ctx, source)
+	}
+	if h.Filename != "" {
+		sourceFileName = h.Filename
+	}
 
 	// Check if the source filename is provided
 	if sourceFileName == "" {
@@ -95,13 +244,55 @@ func (h *HTTPGatherer) Gather(ctx context.Context, source, destination string) (
 	}
 
 	// Validate the destination path
-	err = gogather.ValidateFileDestination(destination)
-	if err != nil {
+	if h.FS != nil {
+		if _, err := h.stat(destination); err == nil {
+			return nil, fmt.Errorf("error validating destination: destination file already exists: %s", destination)
+		}
+	} else if err := gogather.ValidateFileDestination(destination); err != nil {
 		return nil, fmt.Errorf("error validating destination: %w", err)
 	}
 
+	var m metadata.Metadata
+	if h.Checksum != "" {
+		m, err = h.gatherChecksum(ctx, source, destination, sourceFileName, head)
+	} else {
+		m, err = h.download(ctx, source, destination, sourceFileName, head)
+	}
+	if err != nil || h.SignatureKey == nil {
+		return m, err
+	}
+
+	saved := m.(httpMetadata.HTTPMetadata).Destination
+	if err := h.verifySignature(ctx, source, saved); err != nil {
+		_ = h.remove(saved)
+		return nil, err
+	}
+	return m, nil
+}
+
+// download downloads source to the destination file, or to sourceFileName within the destination if it is
+// a directory, given the response to a HEAD request for it if one was sent.
+func (h *HTTPGatherer) download(ctx context.Context, source, destination, sourceFileName string, head *http.Response) (metadata.Metadata, error) {
+	method := h.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	if h.Parallelism > 1 && method == http.MethodGet && h.FS == nil && supportsRanges(head) {
+		if info, err := os.Stat(gogather.ExpandTilde(destination)); err != nil || !info.IsDir() {
+			m, err := h.gatherRanges(ctx, source, destination, head)
+			if !errors.Is(err, errRangesUnsupported) {
+				return m, err
+			}
+		}
+	}
+
 	// Create a new HTTP request
-	req, err := http.NewRequestWithContext(ctx, "GET", source, nil)
+	var body io.Reader
+	if h.Body != nil {
+		body = bytes.NewReader(h.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, source, body)
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %w", err)
 	}
@@ -109,16 +300,80 @@ func (h *HTTPGatherer) Gather(ctx context.Context, source, destination string) (
 	req.Header.Set("User-Agent", "Go-Gather")
 
 	// Send the HTTP request
-	resp, err := h.Client.Do(req)
+	resp, err := h.client(req.URL).Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("error downloading file: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// Check if the response was successful
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("response code error: %d", resp.StatusCode)
+	if err := checkStatus(resp); err != nil {
+		return nil, err
+	}
+
+	if h.ExpectedContentType != "" {
+		if err := checkContentType(resp, h.ExpectedContentType); err != nil {
+			return nil, err
+		}
+	}
+
+	if h.CheckDiskSpace && h.FS == nil {
+		if err := checkDiskSpace(destination, resp.ContentLength); err != nil {
+			return nil, err
+		}
+	}
+
+	s, err := h.saver(destination)
+	if err != nil {
+		return nil, err
+	}
+
+	// Save the downloaded file
+	err = s.Save(ctx, resp.Body, destination)
+	if err != nil && strings.Contains(err.Error(), "is a directory") {
+		destination = filepath.Join(destination, sourceFileName)
+		err = s.Save(ctx, resp.Body, destination)
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		_ = h.remove(destination)
+		return nil, fmt.Errorf("%w: the connection closed before %d bytes were received: %w", ErrContentLengthMismatch, resp.ContentLength, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error saving file: %w", err)
+	}
+
+	// Check the whole body was saved, in case a short body was not reported as an unexpected EOF
+	if resp.ContentLength >= 0 {
+		if info, err := h.stat(destination); err == nil && info.Size() != resp.ContentLength {
+			_ = h.remove(destination)
+			return nil, fmt.Errorf("%w: expected %d bytes, saved %d", ErrContentLengthMismatch, resp.ContentLength, info.Size())
+		}
+	}
+
+	if h.AllowEmpty != nil && !*h.AllowEmpty {
+		if info, err := h.stat(destination); err == nil && info.Size() == 0 {
+			_ = h.remove(destination)
+			return nil, fmt.Errorf("%w: %s", ErrEmptyResponse, source)
+		}
+	}
+
+	// Return the metadata of the downloaded file
+	m := httpMetadata.HTTPMetadata{
+		StatusCode:    resp.StatusCode,
+		ContentLength: resp.ContentLength,
+		Destination:   destination,
+		Headers:       resp.Header,
+		FinalURI:      resp.Request.URL.String(),
+	}
+	return m, nil
+}
+
+// saver returns the FS, if set, or otherwise the Saver for the scheme of the destination
+func (h *HTTPGatherer) saver(destination string) (saver.Saver, error) {
+	if h.FS != nil {
+		return h.FS, nil
 	}
+
 	// Determine the destination type
 	scheme, err := gogather.ClassifyURI(destination)
 	if err != nil {
@@ -130,27 +385,190 @@ func (h *HTTPGatherer) Gather(ctx context.Context, source, destination string) (
 	if err != nil {
 		return nil, fmt.Errorf("error creating saver: %w", err)
 	}
+	return s, nil
+}
 
-	// Save the downloaded file
-	err = s.Save(ctx, resp.Body, destination)
+// open opens the saved file at the destination for reading, from the FS if it is set
+func (h *HTTPGatherer) open(destination string) (fs.File, error) {
+	if h.FS != nil {
+		return h.FS.Open(destination)
+	}
+	return os.Open(localPath(destination))
+}
+
+// stat returns information about the saved file at the destination, from the FS if it is set
+func (h *HTTPGatherer) stat(destination string) (fs.FileInfo, error) {
+	if h.FS == nil {
+		return os.Stat(localPath(destination))
+	}
+	f, err := h.FS.Open(destination)
 	if err != nil {
-		if strings.Contains(err.Error(), "is a directory") {
-			destination = filepath.Join(destination, filepath.Base(src.Path))
-			err = s.Save(ctx, resp.Body, destination)
-			if err != nil {
-				return nil, fmt.Errorf("error saving file: %w", err)
+		return nil, err
+	}
+	defer f.Close()
+	return f.Stat()
+}
+
+// remove removes the saved file at the destination, from the FS if it is set
+func (h *HTTPGatherer) remove(destination string) error {
+	if h.FS != nil {
+		return h.FS.Remove(destination)
+	}
+	return os.Remove(localPath(destination))
+}
+
+// client returns the http.Client used for requests to u, with its redirect policy limited by MaxRedirects
+// if it is set, and a cookie jar holding the Cookies for u if there are any.
+func (h *HTTPGatherer) client(u *url.URL) *http.Client {
+	client := h.Client
+	if len(h.Cookies) > 0 {
+		if client.Jar == nil {
+			// cookiejar.New only fails if given a PublicSuffixList that fails
+			client.Jar, _ = cookiejar.New(nil)
+		}
+		client.Jar.SetCookies(u, h.Cookies)
+	}
+	if h.MaxRedirects != nil {
+		maxRedirects := *h.MaxRedirects
+		client.CheckRedirect = func(_ *http.Request, via []*http.Request) error {
+			if len(via) > maxRedirects {
+				// Stop and return the redirect response, which checkStatus reports
+				return http.ErrUseLastResponse
 			}
-		} else {
-			return nil, fmt.Errorf("error saving file: %w", err)
+			return nil
 		}
 	}
+	return &client
+}
 
-	// Return the metadata of the downloaded file
-	m := httpMetadata.HTTPMetadata{
+// checkStatus returns an error if resp is not a 200 OK response. A redirect that was not followed
+// fails with ErrRedirectNotFollowed, and any other status with an HTTPStatusError.
+func checkStatus(resp *http.Response) error {
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	if location := resp.Header.Get("Location"); location != "" && resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		return fmt.Errorf("%w: %s from %s to %s", ErrRedirectNotFollowed, resp.Status, resp.Request.URL, location)
+	}
+
+	return &HTTPStatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+}
+
+// checkContentType returns ErrUnexpectedContentType if the media type of resp is not expected.
+func checkContentType(resp *http.Response, expected string) error {
+	contentType := resp.Header.Get("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = contentType
+	}
+
+	expectedType, _, err := mime.ParseMediaType(expected)
+	if err != nil {
+		expectedType = expected
+	}
+
+	if !strings.EqualFold(mediaType, expectedType) {
+		return fmt.Errorf("%w: expected %s from %s, got %q", ErrUnexpectedContentType, expectedType, resp.Request.URL, contentType)
+	}
+	return nil
+}
+
+// head sends a HEAD request for source, returning an error if it does not respond with 200 OK.
+func (h *HTTPGatherer) head(ctx context.Context, source string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "HEAD", source, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	req.Header.Set("User-Agent", "Go-Gather")
+
+	resp, err := h.client(req.URL).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error prefetching file: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if err := checkStatus(resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Head sends a HEAD request for source, and returns the metadata of its response without downloading it.
+func (h *HTTPGatherer) Head(ctx context.Context, source string) (metadata.Metadata, error) {
+	if h.Offline {
+		return nil, fmt.Errorf("%w: cannot request %s", gogather.ErrNetworkDisabled, source)
+	}
+
+	resp, err := h.head(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+
+	return httpMetadata.HTTPMetadata{
 		StatusCode:    resp.StatusCode,
 		ContentLength: resp.ContentLength,
-		Destination:   destination,
 		Headers:       resp.Header,
+	}, nil
+}
+
+// Validate checks that source is an absolute http or https URL with a host, without requesting it.
+func (h *HTTPGatherer) Validate(source string) error {
+	src, err := url.Parse(strings.TrimPrefix(source, "http::"))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidSource, err)
 	}
-	return m, nil
+	if src.Scheme != "http" && src.Scheme != "https" {
+		return fmt.Errorf("%w: %s does not have an http or https scheme", ErrInvalidSource, source)
+	}
+	if src.Host == "" {
+		return fmt.Errorf("%w: %s does not have a host", ErrInvalidSource, source)
+	}
+	return nil
+}
+
+// dispositionFilename returns the filename from the Content-Disposition header of resp, or "" if there is none.
+func dispositionFilename(resp *http.Response) string {
+	_, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition"))
+	if err != nil {
+		return ""
+	}
+
+	// Only the base name is used, so the server cannot direct the file outside of the destination
+	name := filepath.Base(filepath.FromSlash(params["filename"]))
+	if name == "." || name == ".." || name == string(filepath.Separator) {
+		return ""
+	}
+	return name
+}
+
+// checkDiskSpace returns ErrInsufficientDiskSpace if size bytes will not fit on the filesystem the destination
+// is written to. The check is skipped if the size is unknown or the free space cannot be determined.
+func checkDiskSpace(destination string, size int64) error {
+	if size <= 0 {
+		return nil
+	}
+
+	// The destination may not exist yet, so check the nearest existing directory it will be created in
+	dir := filepath.Dir(gogather.ExpandTilde(destination))
+	for {
+		if _, err := os.Stat(dir); err == nil || filepath.Dir(dir) == dir {
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+
+	free, err := availableSpace(dir)
+	if err != nil {
+		if errors.Is(err, errDiskFreeUnsupported) {
+			return nil
+		}
+		return fmt.Errorf("failed to determine free disk space: %w", err)
+	}
+
+	if uint64(size) > free {
+		return fmt.Errorf("%w: %d bytes are needed for %s, but only %d are available", ErrInsufficientDiskSpace, size, destination, free)
+	}
+	return nil
 }
@@ -17,18 +17,28 @@
 package http
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
 	"fmt"
+	"io"
+	"io/fs"
 	h "net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
 	"github.com/stretchr/testify/assert"
 
+	gogather "github.com/enterprise-contract/go-gather"
 	"github.com/enterprise-contract/go-gather/metadata/http"
+	"github.com/enterprise-contract/go-gather/saver"
 )
 
 func TestNewHTTPGatherer(t *testing.T) {
@@ -231,6 +241,7 @@ func TestHTTPGatherer_Gather_BadStatusCode(t *testing.T) {
 	if err.Error() != expectedErrMsg {
 		t.Fatalf("expected error message %q but got %q", expectedErrMsg, err.Error())
 	}
+	assert.ErrorIs(t, err, ErrHTTPStatus)
 }
 
 // TestHTTPGatherer_Gather_HTTPError tests the Gather method with an HTTP error.
@@ -308,3 +319,726 @@ func TestHTTPGatherer_Gather_ClassifyURI_Error(t *testing.T) {
 	}
 	assert.EqualError(t, err, "error determining destination type: unsupported source protocol: foo")
 }
+
+// TestHTTPGatherer_Gather_HTTPStatusError tests that non-200 responses return an HTTPStatusError carrying the status code.
+func TestHTTPGatherer_Gather_HTTPStatusError(t *testing.T) {
+	for _, statusCode := range []int{h.StatusNotFound, h.StatusInternalServerError} {
+		t.Run(h.StatusText(statusCode), func(t *testing.T) {
+			mockServer := httptest.NewServer(h.HandlerFunc(func(w h.ResponseWriter, r *h.Request) {
+				w.WriteHeader(statusCode)
+			}))
+			defer mockServer.Close()
+
+			gatherer := NewHTTPGatherer()
+			_, err := gatherer.Gather(context.Background(), fmt.Sprintf("%s/foo.bar", mockServer.URL), t.TempDir())
+
+			var statusErr *HTTPStatusError
+			assert.ErrorAs(t, err, &statusErr)
+			assert.Equal(t, statusCode, statusErr.StatusCode)
+			assert.Equal(t, fmt.Sprintf("%d %s", statusCode, h.StatusText(statusCode)), statusErr.Status)
+			assert.ErrorIs(t, err, ErrHTTPStatus)
+		})
+	}
+}
+
+func TestHTTPGatherer_Gather_Offline(t *testing.T) {
+	requested := false
+	mockServer := httptest.NewServer(h.HandlerFunc(func(w h.ResponseWriter, r *h.Request) {
+		requested = true
+	}))
+	defer mockServer.Close()
+
+	gatherer := NewHTTPGatherer()
+	gatherer.Offline = true
+	_, err := gatherer.Gather(context.Background(), mockServer.URL+"/file.txt", filepath.Join(t.TempDir(), "file.txt"))
+	assert.ErrorIs(t, err, gogather.ErrNetworkDisabled)
+	assert.False(t, requested)
+}
+
+func TestHTTPGatherer_Describe(t *testing.T) {
+	info := NewHTTPGatherer().Describe()
+	assert.Equal(t, "http", info.Name)
+	assert.False(t, info.Extracts)
+	assert.Equal(t, []string{"http::", "http://", "https://"}, info.Prefixes)
+	assert.NotEmpty(t, info.Destination)
+}
+
+func TestHTTPGatherer_Gather_CheckDiskSpace(t *testing.T) {
+	mockServer := httptest.NewServer(h.HandlerFunc(func(w h.ResponseWriter, r *h.Request) {
+		w.Header().Set("Content-Length", "1024")
+		_, _ = w.Write(make([]byte, 1024))
+	}))
+	defer mockServer.Close()
+
+	defer func(original func(string) (uint64, error)) { availableSpace = original }(availableSpace)
+
+	t.Run("insufficient space", func(t *testing.T) {
+		var checked string
+		availableSpace = func(path string) (uint64, error) {
+			checked = path
+			return 512, nil
+		}
+
+		dir := t.TempDir()
+		destination := filepath.Join(dir, "nested", "foo.bar")
+		gatherer := NewHTTPGatherer()
+		gatherer.CheckDiskSpace = true
+		_, err := gatherer.Gather(context.Background(), fmt.Sprintf("%s/foo.bar", mockServer.URL), destination)
+		assert.ErrorIs(t, err, ErrInsufficientDiskSpace)
+		assert.Equal(t, dir, checked)
+		assert.NoFileExists(t, destination)
+	})
+
+	t.Run("sufficient space", func(t *testing.T) {
+		availableSpace = func(string) (uint64, error) { return 2048, nil }
+
+		destination := filepath.Join(t.TempDir(), "foo.bar")
+		gatherer := NewHTTPGatherer()
+		gatherer.CheckDiskSpace = true
+		_, err := gatherer.Gather(context.Background(), fmt.Sprintf("%s/foo.bar", mockServer.URL), destination)
+		assert.NoError(t, err)
+		assert.FileExists(t, destination)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		availableSpace = func(string) (uint64, error) { return 0, nil }
+
+		destination := filepath.Join(t.TempDir(), "foo.bar")
+		_, err := NewHTTPGatherer().Gather(context.Background(), fmt.Sprintf("%s/foo.bar", mockServer.URL), destination)
+		assert.NoError(t, err)
+	})
+
+	t.Run("unsupported platform", func(t *testing.T) {
+		availableSpace = func(string) (uint64, error) { return 0, errDiskFreeUnsupported }
+
+		destination := filepath.Join(t.TempDir(), "foo.bar")
+		gatherer := NewHTTPGatherer()
+		gatherer.CheckDiskSpace = true
+		_, err := gatherer.Gather(context.Background(), fmt.Sprintf("%s/foo.bar", mockServer.URL), destination)
+		assert.NoError(t, err)
+	})
+}
+
+func TestHTTPGatherer_Gather_Filename(t *testing.T) {
+	mockServer := httptest.NewServer(h.HandlerFunc(func(w h.ResponseWriter, r *h.Request) {
+		_, _ = w.Write([]byte("content"))
+	}))
+	defer mockServer.Close()
+
+	for name, suffix := range map[string]string{"trailing slash": "/", "no extension": ""} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			gatherer := NewHTTPGatherer()
+			gatherer.Filename = "custom.txt"
+			m, err := gatherer.Gather(context.Background(), fmt.Sprintf("%s/foo.bar", mockServer.URL), dir+suffix)
+			assert.NoError(t, err)
+			assert.FileExists(t, filepath.Join(dir, "custom.txt"))
+			assert.NoFileExists(t, filepath.Join(dir, "foo.bar"))
+			assert.Equal(t, filepath.Join(dir, "custom.txt"), m.Get()["destination"])
+		})
+	}
+
+	t.Run("file destination", func(t *testing.T) {
+		destination := filepath.Join(t.TempDir(), "explicit.txt")
+		gatherer := NewHTTPGatherer()
+		gatherer.Filename = "custom.txt"
+		_, err := gatherer.Gather(context.Background(), fmt.Sprintf("%s/foo.bar", mockServer.URL), destination)
+		assert.NoError(t, err)
+		assert.FileExists(t, destination)
+	})
+}
+
+func TestHTTPGatherer_Gather_Prefetch(t *testing.T) {
+	var methods []string
+	mockServer := httptest.NewServer(h.HandlerFunc(func(w h.ResponseWriter, r *h.Request) {
+		methods = append(methods, r.Method)
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(h.StatusNotFound)
+			return
+		case "/download":
+			w.Header().Set("Content-Disposition", `attachment; filename="report.txt"`)
+		case "/escape":
+			w.Header().Set("Content-Disposition", `attachment; filename="../../escape.txt"`)
+		}
+		if r.Method == h.MethodGet {
+			_, _ = w.Write([]byte("content"))
+		}
+	}))
+	defer mockServer.Close()
+
+	t.Run("filename from Content-Disposition", func(t *testing.T) {
+		methods = nil
+		dir := t.TempDir()
+		gatherer := NewHTTPGatherer()
+		gatherer.Prefetch = true
+		m, err := gatherer.Gather(context.Background(), mockServer.URL+"/download", dir+"/")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{h.MethodHead, h.MethodGet}, methods)
+		assert.FileExists(t, filepath.Join(dir, "report.txt"))
+		assert.Equal(t, filepath.Join(dir, "report.txt"), m.Get()["destination"])
+	})
+
+	t.Run("filename stays within the destination", func(t *testing.T) {
+		dir := t.TempDir()
+		gatherer := NewHTTPGatherer()
+		gatherer.Prefetch = true
+		_, err := gatherer.Gather(context.Background(), mockServer.URL+"/escape", dir+"/")
+		assert.NoError(t, err)
+		assert.FileExists(t, filepath.Join(dir, "escape.txt"))
+	})
+
+	t.Run("no Content-Disposition", func(t *testing.T) {
+		dir := t.TempDir()
+		gatherer := NewHTTPGatherer()
+		gatherer.Prefetch = true
+		_, err := gatherer.Gather(context.Background(), mockServer.URL+"/foo.bar", dir+"/")
+		assert.NoError(t, err)
+		assert.FileExists(t, filepath.Join(dir, "foo.bar"))
+	})
+
+	t.Run("missing resource", func(t *testing.T) {
+		methods = nil
+		gatherer := NewHTTPGatherer()
+		gatherer.Prefetch = true
+		_, err := gatherer.Gather(context.Background(), mockServer.URL+"/missing", t.TempDir()+"/")
+		assert.ErrorIs(t, err, ErrHTTPStatus)
+		assert.Equal(t, []string{h.MethodHead}, methods)
+	})
+}
+
+func TestHTTPGatherer_Gather_MaxRedirects(t *testing.T) {
+	mockServer := httptest.NewServer(h.HandlerFunc(func(w h.ResponseWriter, r *h.Request) {
+		switch r.URL.Path {
+		case "/first.txt":
+			h.Redirect(w, r, "/second.txt", h.StatusFound)
+		case "/second.txt":
+			h.Redirect(w, r, "/file.txt", h.StatusMovedPermanently)
+		default:
+			_, _ = w.Write([]byte("content"))
+		}
+	}))
+	defer mockServer.Close()
+
+	testCases := []struct {
+		name         string
+		maxRedirects *int
+		err          string
+	}{
+		{name: "client policy", maxRedirects: nil},
+		{name: "disabled", maxRedirects: new(int), err: "redirect not followed: 302 Found from " + mockServer.URL + "/first.txt to /second.txt"},
+		{name: "too many", maxRedirects: func() *int { i := 1; return &i }(), err: "redirect not followed: 301 Moved Permanently from " + mockServer.URL + "/second.txt to /file.txt"},
+		{name: "enough", maxRedirects: func() *int { i := 2; return &i }()},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			destination := filepath.Join(t.TempDir(), "file.txt")
+			gatherer := NewHTTPGatherer()
+			gatherer.MaxRedirects = tc.maxRedirects
+			_, err := gatherer.Gather(context.Background(), mockServer.URL+"/first.txt", destination)
+			if tc.err != "" {
+				assert.ErrorIs(t, err, ErrRedirectNotFollowed)
+				assert.EqualError(t, err, tc.err)
+				assert.NoFileExists(t, destination)
+				return
+			}
+			assert.NoError(t, err)
+			assert.FileExists(t, destination)
+		})
+	}
+}
+
+func TestHTTPGatherer_Gather_ExpectedContentType(t *testing.T) {
+	mockServer := httptest.NewServer(h.HandlerFunc(func(w h.ResponseWriter, r *h.Request) {
+		if r.URL.Path == "/archive.tar.gz" {
+			w.Header().Set("Content-Type", "application/gzip")
+			_, _ = w.Write([]byte("content"))
+			return
+		}
+		// A "soft 404" error page served with a 200 status
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte("<html>Not Found</html>"))
+	}))
+	defer mockServer.Close()
+
+	testCases := []struct {
+		name     string
+		path     string
+		expected string
+		err      bool
+	}{
+		{name: "matching", path: "/archive.tar.gz", expected: "application/gzip"},
+		{name: "matching ignoring case and parameters", path: "/page.html", expected: "Text/HTML"},
+		{name: "html page", path: "/missing.tar.gz", expected: "application/gzip", err: true},
+		{name: "not checked", path: "/missing.tar.gz", expected: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			destination := filepath.Join(t.TempDir(), "file.out")
+			gatherer := NewHTTPGatherer()
+			gatherer.ExpectedContentType = tc.expected
+			_, err := gatherer.Gather(context.Background(), mockServer.URL+tc.path, destination)
+			if tc.err {
+				assert.ErrorIs(t, err, ErrUnexpectedContentType)
+				assert.NoFileExists(t, destination)
+				return
+			}
+			assert.NoError(t, err)
+			assert.FileExists(t, destination)
+		})
+	}
+}
+
+func TestHTTPGatherer_Gather_Parallelism(t *testing.T) {
+	content := make([]byte, 1000)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+
+	var mu sync.Mutex
+	var ranges []string
+	var heads int
+	mockServer := httptest.NewServer(h.HandlerFunc(func(w h.ResponseWriter, r *h.Request) {
+		mu.Lock()
+		if r.Header.Get("Range") != "" {
+			ranges = append(ranges, r.Header.Get("Range"))
+		}
+		if r.Method == h.MethodHead {
+			heads++
+		}
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/file.bin":
+			h.ServeContent(w, r, "file.bin", time.Time{}, bytes.NewReader(content))
+		case "/no-ranges.bin":
+			// Content-Length is reported, but not Accept-Ranges
+			w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+			_, _ = w.Write(content)
+		case "/no-head.bin":
+			if r.Method == h.MethodHead {
+				w.WriteHeader(h.StatusMethodNotAllowed)
+				return
+			}
+			h.ServeContent(w, r, "no-head.bin", time.Time{}, bytes.NewReader(content))
+		case "/ignored-ranges.bin":
+			// Accept-Ranges is reported, but the Range header is ignored
+			w.Header().Set("Accept-Ranges", "bytes")
+			w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+			if r.Method != h.MethodHead {
+				_, _ = w.Write(content)
+			}
+		case "/broken.bin":
+			if r.Header.Get("Range") == "bytes=0-499" {
+				w.WriteHeader(h.StatusInternalServerError)
+				return
+			}
+			h.ServeContent(w, r, "broken.bin", time.Time{}, bytes.NewReader(content))
+		}
+	}))
+	defer mockServer.Close()
+
+	t.Run("ranges", func(t *testing.T) {
+		ranges = nil
+		destination := filepath.Join(t.TempDir(), "file.bin")
+		gatherer := NewHTTPGatherer()
+		gatherer.Parallelism = 3
+		m, err := gatherer.Gather(context.Background(), mockServer.URL+"/file.bin", destination)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{"bytes=0-333", "bytes=334-667", "bytes=668-999"}, ranges)
+		assert.Equal(t, int64(len(content)), m.Get()["contentLength"])
+
+		downloaded, err := os.ReadFile(destination)
+		assert.NoError(t, err)
+		assert.Equal(t, content, downloaded)
+	})
+
+	t.Run("no range support", func(t *testing.T) {
+		ranges = nil
+		destination := filepath.Join(t.TempDir(), "file.bin")
+		gatherer := NewHTTPGatherer()
+		gatherer.Parallelism = 3
+		_, err := gatherer.Gather(context.Background(), mockServer.URL+"/no-ranges.bin", destination)
+		assert.NoError(t, err)
+		assert.Empty(t, ranges)
+
+		downloaded, err := os.ReadFile(destination)
+		assert.NoError(t, err)
+		assert.Equal(t, content, downloaded)
+	})
+
+	t.Run("HEAD rejected", func(t *testing.T) {
+		ranges = nil
+		destination := filepath.Join(t.TempDir(), "file.bin")
+		gatherer := NewHTTPGatherer()
+		gatherer.Parallelism = 3
+		_, err := gatherer.Gather(context.Background(), mockServer.URL+"/no-head.bin", destination)
+		assert.NoError(t, err)
+		assert.Empty(t, ranges)
+
+		downloaded, err := os.ReadFile(destination)
+		assert.NoError(t, err)
+		assert.Equal(t, content, downloaded)
+	})
+
+	t.Run("Range ignored", func(t *testing.T) {
+		destination := filepath.Join(t.TempDir(), "file.bin")
+		gatherer := NewHTTPGatherer()
+		gatherer.Parallelism = 3
+		_, err := gatherer.Gather(context.Background(), mockServer.URL+"/ignored-ranges.bin", destination)
+		assert.NoError(t, err)
+
+		downloaded, err := os.ReadFile(destination)
+		assert.NoError(t, err)
+		assert.Equal(t, content, downloaded)
+		entries, err := os.ReadDir(filepath.Dir(destination))
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+	})
+
+	t.Run("no HEAD for other methods", func(t *testing.T) {
+		ranges, heads = nil, 0
+		destination := filepath.Join(t.TempDir(), "file.bin")
+		gatherer := NewHTTPGatherer()
+		gatherer.Parallelism = 3
+		gatherer.Method = h.MethodPost
+		_, err := gatherer.Gather(context.Background(), mockServer.URL+"/file.bin", destination)
+		assert.NoError(t, err)
+		assert.Empty(t, ranges)
+		assert.Zero(t, heads)
+	})
+
+	t.Run("failed range", func(t *testing.T) {
+		destination := filepath.Join(t.TempDir(), "file.bin")
+		gatherer := NewHTTPGatherer()
+		gatherer.Parallelism = 2
+		_, err := gatherer.Gather(context.Background(), mockServer.URL+"/broken.bin", destination)
+		assert.ErrorIs(t, err, ErrHTTPStatus)
+		assert.NoFileExists(t, destination)
+	})
+}
+
+func TestHTTPGatherer_Gather_Cookies(t *testing.T) {
+	mockServer := httptest.NewServer(h.HandlerFunc(func(w h.ResponseWriter, r *h.Request) {
+		if c, err := r.Cookie("session"); err != nil || c.Value != "secret" {
+			w.WriteHeader(h.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte("content"))
+	}))
+	defer mockServer.Close()
+
+	t.Run("session cookie", func(t *testing.T) {
+		destination := filepath.Join(t.TempDir(), "file.txt")
+		gatherer := NewHTTPGatherer()
+		gatherer.Cookies = []*h.Cookie{{Name: "session", Value: "secret"}}
+		_, err := gatherer.Gather(context.Background(), mockServer.URL+"/file.txt", destination)
+		assert.NoError(t, err)
+		assert.FileExists(t, destination)
+	})
+
+	t.Run("with prefetch", func(t *testing.T) {
+		destination := filepath.Join(t.TempDir(), "file.txt")
+		gatherer := NewHTTPGatherer()
+		gatherer.Prefetch = true
+		gatherer.Cookies = []*h.Cookie{{Name: "session", Value: "secret"}}
+		_, err := gatherer.Gather(context.Background(), mockServer.URL+"/file.txt", destination)
+		assert.NoError(t, err)
+	})
+
+	t.Run("no cookie", func(t *testing.T) {
+		gatherer := NewHTTPGatherer()
+		_, err := gatherer.Gather(context.Background(), mockServer.URL+"/file.txt", filepath.Join(t.TempDir(), "file.txt"))
+		assert.ErrorIs(t, err, ErrHTTPStatus)
+	})
+
+	t.Run("cookie for another host", func(t *testing.T) {
+		gatherer := NewHTTPGatherer()
+		gatherer.Cookies = []*h.Cookie{{Name: "session", Value: "secret", Domain: "example.com"}}
+		_, err := gatherer.Gather(context.Background(), mockServer.URL+"/file.txt", filepath.Join(t.TempDir(), "file.txt"))
+		assert.ErrorIs(t, err, ErrHTTPStatus)
+	})
+}
+
+// TestHTTPGatherer_Head tests getting the metadata of a file without downloading it.
+func TestHTTPGatherer_Head(t *testing.T) {
+	server := httptest.NewServer(h.HandlerFunc(func(w h.ResponseWriter, r *h.Request) {
+		assert.Equal(t, h.MethodHead, r.Method)
+		if r.URL.Path != "/file.txt" {
+			w.WriteHeader(h.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", "7")
+		w.Header().Set("Last-Modified", "Wed, 21 Oct 2015 07:28:00 GMT")
+	}))
+	defer server.Close()
+
+	m, err := NewHTTPGatherer().Head(context.Background(), server.URL+"/file.txt")
+	assert.NoError(t, err)
+	metadata := m.(http.HTTPMetadata)
+	assert.Equal(t, h.StatusOK, metadata.StatusCode)
+	assert.Equal(t, int64(7), metadata.ContentLength)
+	assert.Equal(t, "Wed, 21 Oct 2015 07:28:00 GMT", metadata.Headers["Last-Modified"][0])
+
+	_, err = NewHTTPGatherer().Head(context.Background(), server.URL+"/missing.txt")
+	var statusErr *HTTPStatusError
+	assert.ErrorAs(t, err, &statusErr)
+
+	_, err = (&HTTPGatherer{Offline: true}).Head(context.Background(), server.URL+"/file.txt")
+	assert.ErrorIs(t, err, gogather.ErrNetworkDisabled)
+}
+
+// TestHTTPGatherer_Gather_Cache tests that a file with a cached checksum is copied from the cache rather than downloaded.
+func TestHTTPGatherer_Gather_Cache(t *testing.T) {
+	var gets int
+	server := httptest.NewServer(h.HandlerFunc(func(w h.ResponseWriter, r *h.Request) {
+		gets++
+		_, _ = w.Write([]byte("policy"))
+	}))
+	defer server.Close()
+
+	digest := fmt.Sprintf("sha256:%x", sha256.Sum256([]byte("policy")))
+
+	gatherer := NewHTTPGatherer()
+	gatherer.Checksum = digest
+	gatherer.Cache = &gogather.DirCache{Dir: t.TempDir()}
+
+	for i := 0; i < 2; i++ {
+		destination := filepath.Join(t.TempDir(), "policy.txt")
+		m, err := gatherer.Gather(context.Background(), server.URL+"/policy.txt", destination)
+		assert.NoError(t, err)
+		assert.Equal(t, destination, m.(http.HTTPMetadata).Destination)
+
+		content, err := os.ReadFile(destination)
+		assert.NoError(t, err)
+		assert.Equal(t, "policy", string(content))
+	}
+	assert.Equal(t, 1, gets)
+
+	gatherer.Checksum = fmt.Sprintf("sha256:%x", sha256.Sum256([]byte("other")))
+	destination := filepath.Join(t.TempDir(), "policy.txt")
+	_, err := gatherer.Gather(context.Background(), server.URL+"/policy.txt", destination)
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+	assert.NoFileExists(t, destination)
+}
+
+// TestHTTPGatherer_Gather_ContentLengthMismatch tests that a response shorter than its Content-Length fails and is not kept.
+func TestHTTPGatherer_Gather_ContentLengthMismatch(t *testing.T) {
+	server := httptest.NewServer(h.HandlerFunc(func(w h.ResponseWriter, r *h.Request) {
+		w.Header().Set("Content-Length", "100")
+		_, _ = w.Write([]byte("short body"))
+	}))
+	defer server.Close()
+
+	destination := filepath.Join(t.TempDir(), "file.txt")
+	_, err := NewHTTPGatherer().Gather(context.Background(), server.URL+"/file.txt", destination)
+	assert.ErrorIs(t, err, ErrContentLengthMismatch)
+	assert.NoFileExists(t, destination)
+}
+
+func TestHTTPGatherer_Validate(t *testing.T) {
+	h := &HTTPGatherer{}
+	assert.NoError(t, h.Validate("https://example.com/file.txt"))
+	assert.NoError(t, h.Validate("http::http://example.com/file.txt"))
+	assert.ErrorIs(t, h.Validate("ftp://example.com/file.txt"), ErrInvalidSource)
+	assert.ErrorIs(t, h.Validate("https:///file.txt"), ErrInvalidSource)
+	assert.ErrorIs(t, h.Validate("https://exa mple.com/%zz"), ErrInvalidSource)
+}
+
+func TestHTTPGatherer_Gather_Method(t *testing.T) {
+	mockServer := httptest.NewServer(h.HandlerFunc(func(w h.ResponseWriter, r *h.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != h.MethodPost || string(body) != "token" {
+			w.WriteHeader(h.StatusMethodNotAllowed)
+			return
+		}
+		_, _ = w.Write([]byte("content"))
+	}))
+	defer mockServer.Close()
+
+	t.Run("POST with a body", func(t *testing.T) {
+		destination := filepath.Join(t.TempDir(), "file.txt")
+		gatherer := NewHTTPGatherer()
+		gatherer.Method = h.MethodPost
+		gatherer.Body = []byte("token")
+		_, err := gatherer.Gather(context.Background(), mockServer.URL+"/file.txt", destination)
+		assert.NoError(t, err)
+		content, err := os.ReadFile(destination)
+		assert.NoError(t, err)
+		assert.Equal(t, "content", string(content))
+	})
+
+	t.Run("GET by default", func(t *testing.T) {
+		destination := filepath.Join(t.TempDir(), "file.txt")
+		_, err := NewHTTPGatherer().Gather(context.Background(), mockServer.URL+"/file.txt", destination)
+		var statusErr *HTTPStatusError
+		assert.ErrorAs(t, err, &statusErr)
+		assert.Equal(t, h.StatusMethodNotAllowed, statusErr.StatusCode)
+	})
+}
+
+func TestHTTPGatherer_Gather_AllowEmpty(t *testing.T) {
+	mockServer := httptest.NewServer(h.HandlerFunc(func(w h.ResponseWriter, r *h.Request) {}))
+	defer mockServer.Close()
+
+	allow, reject := true, false
+	for _, allowEmpty := range []*bool{nil, &allow} {
+		destination := filepath.Join(t.TempDir(), "file.txt")
+		gatherer := NewHTTPGatherer()
+		gatherer.AllowEmpty = allowEmpty
+		_, err := gatherer.Gather(context.Background(), mockServer.URL+"/file.txt", destination)
+		assert.NoError(t, err)
+		info, err := os.Stat(destination)
+		assert.NoError(t, err)
+		assert.Zero(t, info.Size())
+	}
+
+	destination := filepath.Join(t.TempDir(), "file.txt")
+	gatherer := NewHTTPGatherer()
+	gatherer.AllowEmpty = &reject
+	_, err := gatherer.Gather(context.Background(), mockServer.URL+"/file.txt", destination)
+	assert.ErrorIs(t, err, ErrEmptyResponse)
+	assert.NoFileExists(t, destination)
+}
+
+func TestHTTPGatherer_Gather_Mirrors(t *testing.T) {
+	var requested []string
+	mockServer := httptest.NewServer(h.HandlerFunc(func(w h.ResponseWriter, r *h.Request) {
+		requested = append(requested, r.URL.Path)
+		if r.URL.Path != "/second/file.txt" {
+			w.WriteHeader(h.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte("content"))
+	}))
+	defer mockServer.Close()
+
+	destination := filepath.Join(t.TempDir(), "file.txt")
+	gatherer := NewHTTPGatherer()
+	gatherer.Mirrors = []string{mockServer.URL + "/first/file.txt", mockServer.URL + "/second/file.txt", mockServer.URL + "/third/file.txt"}
+	m, err := gatherer.Gather(context.Background(), mockServer.URL+"/file.txt", destination)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/file.txt", "/first/file.txt", "/second/file.txt"}, requested)
+	assert.Equal(t, mockServer.URL+"/second/file.txt", m.Get()["finalURI"])
+	content, err := os.ReadFile(destination)
+	assert.NoError(t, err)
+	assert.Equal(t, "content", string(content))
+
+	// The error of the last mirror is returned if none succeeds
+	gatherer.Mirrors = []string{mockServer.URL + "/first/file.txt"}
+	_, err = gatherer.Gather(context.Background(), mockServer.URL+"/file.txt", filepath.Join(t.TempDir(), "file.txt"))
+	assert.ErrorIs(t, err, ErrHTTPStatus)
+}
+
+func TestHTTPGatherer_Gather_MetadataNotShared(t *testing.T) {
+	mockServer := httptest.NewServer(h.HandlerFunc(func(w h.ResponseWriter, r *h.Request) {
+		w.Header().Set("X-Path", r.URL.Path)
+		_, _ = w.Write([]byte(r.URL.Path))
+	}))
+	defer mockServer.Close()
+
+	gatherer := NewHTTPGatherer()
+	first, err := gatherer.Gather(context.Background(), mockServer.URL+"/first.txt", filepath.Join(t.TempDir(), "first.txt"))
+	assert.NoError(t, err)
+	want := first.Get()
+
+	// A second gather leaves the metadata returned by the first as it was
+	_, err = gatherer.Gather(context.Background(), mockServer.URL+"/second/file.txt", filepath.Join(t.TempDir(), "file.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, want, first.Get())
+	assert.Equal(t, int64(len("/first.txt")), first.Get()["contentLength"])
+	assert.Equal(t, []string{"/first.txt"}, first.Get()["headers"].(map[string][]string)["X-Path"])
+}
+
+func TestHTTPGatherer_Gather_SignatureKey(t *testing.T) {
+	config := &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA}
+	signer, err := openpgp.NewEntity("signer", "", "signer@example.com", config)
+	assert.NoError(t, err)
+	other, err := openpgp.NewEntity("other", "", "other@example.com", config)
+	assert.NoError(t, err)
+
+	content := []byte("release artifact")
+	var armored, binary bytes.Buffer
+	assert.NoError(t, openpgp.ArmoredDetachSign(&armored, signer, bytes.NewReader(content), config))
+	assert.NoError(t, openpgp.DetachSign(&binary, signer, bytes.NewReader(content), config))
+
+	mockServer := httptest.NewServer(h.HandlerFunc(func(w h.ResponseWriter, r *h.Request) {
+		switch r.URL.Path {
+		case "/file.txt":
+			_, _ = w.Write(content)
+		case "/file.txt.asc":
+			_, _ = w.Write(armored.Bytes())
+		case "/file.txt.sig":
+			_, _ = w.Write(binary.Bytes())
+		default:
+			w.WriteHeader(h.StatusNotFound)
+		}
+	}))
+	defer mockServer.Close()
+
+	publicKey := func(e *openpgp.Entity) []byte {
+		var buf bytes.Buffer
+		w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+		assert.NoError(t, err)
+		assert.NoError(t, e.Serialize(w))
+		assert.NoError(t, w.Close())
+		return buf.Bytes()
+	}
+
+	tests := []struct {
+		name     string
+		key      []byte
+		suffix   string
+		expected error
+	}{
+		{"armored signature", publicKey(signer), "", nil},
+		{"binary signature", publicKey(signer), ".sig", nil},
+		{"other key", publicKey(other), "", ErrSignatureMismatch},
+		{"missing signature", publicKey(signer), ".minisig", ErrHTTPStatus},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			destination := filepath.Join(t.TempDir(), "file.txt")
+			gatherer := NewHTTPGatherer()
+			gatherer.SignatureKey = tt.key
+			gatherer.SignatureSuffix = tt.suffix
+			_, err := gatherer.Gather(context.Background(), mockServer.URL+"/file.txt", destination)
+			if tt.expected != nil {
+				assert.ErrorIs(t, err, tt.expected)
+				assert.NoFileExists(t, destination)
+				return
+			}
+			assert.NoError(t, err)
+			saved, err := os.ReadFile(destination)
+			assert.NoError(t, err)
+			assert.Equal(t, content, saved)
+		})
+	}
+}
+
+func TestHTTPGatherer_Gather_FS(t *testing.T) {
+	mockServer := httptest.NewServer(h.HandlerFunc(func(w h.ResponseWriter, r *h.Request) {
+		_, _ = w.Write([]byte("content"))
+	}))
+	defer mockServer.Close()
+
+	memory := saver.NewMemoryFS()
+	destination := filepath.Join(t.TempDir(), "gathered")
+	gatherer := NewHTTPGatherer()
+	gatherer.FS = memory
+	m, err := gatherer.Gather(context.Background(), mockServer.URL+"/file.txt", destination+"/")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(destination, "file.txt"), m.Get()["destination"])
+	content, err := fs.ReadFile(saver.Sub(memory, destination), "file.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "content", string(content))
+	assert.NoDirExists(t, destination)
+
+	// A file that fails verification is removed from the FS
+	gatherer.Checksum = fmt.Sprintf("sha256:%x", sha256.Sum256([]byte("other")))
+	_, err = gatherer.Gather(context.Background(), mockServer.URL+"/other.txt", destination+"/")
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+	_, err = memory.Open(filepath.Join(destination, "other.txt"))
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
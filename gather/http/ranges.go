@@ -0,0 +1,157 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	gogather "github.com/enterprise-contract/go-gather"
+	"github.com/enterprise-contract/go-gather/metadata"
+	httpMetadata "github.com/enterprise-contract/go-gather/metadata/http"
+	"github.com/enterprise-contract/go-gather/saver/file"
+)
+
+// errRangesUnsupported is returned by gatherRanges if a range request is not answered with the range, so
+// the file is downloaded in a single request instead.
+var errRangesUnsupported = errors.New("range requests are not supported")
+
+// supportsRanges reports whether the HEAD response resp shows the resource can be downloaded in byte ranges
+func supportsRanges(resp *http.Response) bool {
+	return resp != nil && resp.Header.Get("Accept-Ranges") == "bytes" && resp.ContentLength > 0
+}
+
+// gatherRanges downloads source into the destination file as Parallelism byte ranges fetched concurrently,
+// given the response to a HEAD request for it. The ranges are written to a file.PartFile, which replaces the
+// destination once complete and is removed if any range fails.
+func (h *HTTPGatherer) gatherRanges(ctx context.Context, source, destination string, head *http.Response) (metadata.Metadata, error) {
+	if h.ExpectedContentType != "" {
+		if err := checkContentType(head, h.ExpectedContentType); err != nil {
+			return nil, err
+		}
+	}
+
+	size := head.ContentLength
+	if h.CheckDiskSpace {
+		if err := checkDiskSpace(destination, size); err != nil {
+			return nil, err
+		}
+	}
+
+	path := gogather.ExpandTilde(destination)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("error creating directory: %w", err)
+	}
+
+	f, err := file.CreatePart(path)
+	if err != nil {
+		return nil, fmt.Errorf("error creating file: %w", err)
+	}
+	defer f.Discard()
+
+	if err := f.Truncate(size); err != nil {
+		return nil, fmt.Errorf("error allocating file: %w", err)
+	}
+
+	parts := int64(h.Parallelism)
+	if parts > size {
+		parts = size
+	}
+	partSize := (size + parts - 1) / parts
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errChan := make(chan error, parts)
+	var wg sync.WaitGroup
+	for start := int64(0); start < size; start += partSize {
+		end := start + partSize - 1
+		if end >= size {
+			end = size - 1
+		}
+
+		wg.Add(1)
+		go func(start, end int64) {
+			defer wg.Done()
+			if err := h.downloadRange(ctx, source, f.File, start, end); err != nil {
+				// Send the error before cancelling, so that it is received ahead of those caused by the cancellation
+				errChan <- err
+				cancel()
+			}
+		}(start, end)
+	}
+	wg.Wait()
+	close(errChan)
+
+	if err := <-errChan; err != nil {
+		return nil, fmt.Errorf("error downloading file: %w", err)
+	}
+
+	if err := f.Commit(); err != nil {
+		return nil, fmt.Errorf("error writing file: %w", err)
+	}
+
+	return httpMetadata.HTTPMetadata{
+		StatusCode:    head.StatusCode,
+		ContentLength: size,
+		Destination:   destination,
+		Headers:       head.Header,
+		FinalURI:      head.Request.URL.String(),
+	}, nil
+}
+
+// downloadRange downloads the bytes from start to end, inclusive, of source into the same offsets of f
+func (h *HTTPGatherer) downloadRange(ctx context.Context, source string, f *os.File, start, end int64) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", source, nil)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+
+	req.Header.Set("User-Agent", "Go-Gather")
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+
+	resp, err := h.client(req.URL).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// A server that ignores the Range header sends the whole file, and one that does not support the
+	// range may reject it as not satisfiable
+	if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		return errRangesUnsupported
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		return &HTTPStatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	length := end - start + 1
+	n, err := io.Copy(io.NewOffsetWriter(f, start), io.LimitReader(resp.Body, length))
+	if err != nil {
+		return err
+	}
+	if n != length {
+		return fmt.Errorf("range %d-%d: %w", start, end, io.ErrUnexpectedEOF)
+	}
+	return nil
+}
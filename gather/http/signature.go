@@ -0,0 +1,100 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+// maxSignatureSize is the largest detached signature downloaded, well above the size of any real signature
+const maxSignatureSize = 1 << 20
+
+// armorPrefix starts an ASCII armored OpenPGP signature or key
+var armorPrefix = []byte("-----BEGIN PGP")
+
+// verifySignature checks that the saved file at the destination has a valid detached signature by the SignatureKey,
+// downloading the signature from source with the SignatureSuffix appended.
+func (h *HTTPGatherer) verifySignature(ctx context.Context, source, destination string) error {
+	keyring, err := readKeyRing(h.SignatureKey)
+	if err != nil {
+		return fmt.Errorf("error reading signature key: %w", err)
+	}
+
+	suffix := h.SignatureSuffix
+	if suffix == "" {
+		suffix = ".asc"
+	}
+	signature, err := h.downloadSignature(ctx, source+suffix)
+	if err != nil {
+		return err
+	}
+
+	f, err := h.open(destination)
+	if err != nil {
+		return fmt.Errorf("error reading downloaded file: %w", err)
+	}
+	defer f.Close()
+
+	if bytes.HasPrefix(bytes.TrimSpace(signature), armorPrefix) {
+		_, err = openpgp.CheckArmoredDetachedSignature(keyring, f, bytes.NewReader(signature), nil)
+	} else {
+		_, err = openpgp.CheckDetachedSignature(keyring, f, bytes.NewReader(signature), nil)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrSignatureMismatch, source, err)
+	}
+	return nil
+}
+
+// downloadSignature returns the detached signature at source
+func (h *HTTPGatherer) downloadSignature(ctx context.Context, source string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", "Go-Gather")
+
+	resp, err := h.client(req.URL).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading signature: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if err := checkStatus(resp); err != nil {
+		return nil, fmt.Errorf("error downloading signature: %w", err)
+	}
+
+	signature, err := io.ReadAll(io.LimitReader(resp.Body, maxSignatureSize))
+	if err != nil {
+		return nil, fmt.Errorf("error downloading signature: %w", err)
+	}
+	return signature, nil
+}
+
+// readKeyRing reads an armored or binary OpenPGP public key
+func readKeyRing(key []byte) (openpgp.EntityList, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(key), armorPrefix) {
+		return openpgp.ReadArmoredKeyRing(bytes.NewReader(key))
+	}
+	return openpgp.ReadKeyRing(bytes.NewReader(key))
+}
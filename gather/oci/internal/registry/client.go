@@ -12,7 +12,9 @@ import (
 	"github.com/enterprise-contract/go-gather/gather/oci/internal/network"
 )
 
-func SetupClient(repository *remote.Repository) error {
+// SetupClient sets up the client of the repository to send its requests with the transport, retrying
+// failed requests, or with http.DefaultTransport if transport is nil.
+func SetupClient(repository *remote.Repository, transport http.RoundTripper) error {
 	registry := repository.Reference.Host()
 
 	// If `--tls=false` was provided or accessing the registry via loopback with
@@ -22,8 +24,11 @@ func SetupClient(repository *remote.Repository) error {
 		repository.PlainHTTP = true
 	}
 
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
 	httpClient := &http.Client{
-		Transport: retry.NewTransport(http.DefaultTransport),
+		Transport: retry.NewTransport(transport),
 	}
 
 	store, err := credentials.NewStoreFromDocker(credentials.StoreOptions{
@@ -22,42 +22,273 @@ package oci
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
+	"text/template"
 
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
 	"oras.land/oras-go/v2/content/file"
+	"oras.land/oras-go/v2/content/memory"
 	"oras.land/oras-go/v2/registry"
 	"oras.land/oras-go/v2/registry/remote"
 
+	gogather "github.com/enterprise-contract/go-gather"
 	r "github.com/enterprise-contract/go-gather/gather/oci/internal/registry"
 	"github.com/enterprise-contract/go-gather/metadata"
 	"github.com/enterprise-contract/go-gather/metadata/oci"
 )
 
+var (
+	// ErrDestinationNotEmpty is returned when the destination directory is not empty and Overwrite is not set.
+	ErrDestinationNotEmpty = errors.New("destination directory is not empty")
+	// ErrArtifactTypeMismatch is returned when the type of the artifact is not the ExpectedArtifactType.
+	ErrArtifactTypeMismatch = errors.New("unexpected artifact type")
+	// ErrInvalidNameTemplate is returned when the NameTemplate cannot be parsed, or does not render a distinct file name for each layer.
+	ErrInvalidNameTemplate = errors.New("invalid name template")
+	// ErrLayerTitleConflict is returned when two layers of the artifact have the same title, or a title that is not
+	// a path within the destination directory, so they cannot be written to the files they are titled with.
+	ErrLayerTitleConflict = errors.New("conflicting layer titles")
+	// ErrInvalidReference is returned by Validate when the source is not a valid artifact reference.
+	ErrInvalidReference = errors.New("invalid OCI reference")
+	// ErrOCINoRepository is returned when the source does not name a repository within a registry, e.g. "oci://registry".
+	ErrOCINoRepository = errors.New("no repository in OCI reference")
+)
+
 // OCIGatherer is a struct that implements the Gatherer interface
 // and provides methods for gathering from OCI.
-type OCIGatherer struct{}
+type OCIGatherer struct {
+	// Overwrite allows gathering into a destination directory that is not empty,
+	// replacing any files with the same names as the files in the artifact.
+	Overwrite bool
+	// Offline makes Gather fail immediately with gogather.ErrNetworkDisabled.
+	Offline bool
+	// Platform selects the manifest gathered when the source is an image index. The digest in the
+	// returned metadata is then that of the selected manifest, rather than of the index.
+	Platform *ocispec.Platform
+	// ExpectedArtifactType is the type the artifact must have to be gathered. The type of an artifact is the
+	// artifactType of its manifest or, if that is not set, the media type of its config. If empty, any type is accepted.
+	ExpectedArtifactType string
+	// NameTemplate is a text/template rendering the name of the file each layer is written to, given its
+	// LayerName. If it is set, every layer is written as is, including layers that would otherwise be unpacked.
+	// Path separators in a rendered name are replaced by underscores. Layers are streamed from the registry to
+	// their files, so only the manifest is held in memory.
+	NameTemplate string
+	// Cache holds artifacts by their manifest digest. If it is set, an artifact that is cached is copied from the
+	// Cache rather than pulled, and an artifact pulled into an empty destination is added to it. It is not used
+	// when Platform is set.
+	Cache gogather.Cache
+	// DirMode is the mode the destination directory is created with, before the umask is applied.
+	// If zero, gogather.DefaultDirMode is used.
+	DirMode os.FileMode
+	// Transport sends the requests to the registry, which are retried if they fail. Sharing a Transport between
+	// gathers reuses its connections to the registry. If nil, http.DefaultTransport is used.
+	Transport http.RoundTripper
+}
+
+// Describe returns information about the sources handled by the OCIGatherer and how it writes them.
+func (f *OCIGatherer) Describe() gogather.GathererInfo {
+	return gogather.GathererInfo{
+		Name:        "oci",
+		Prefixes:    []string{"oci::", "oci://"},
+		Extracts:    false,
+		Destination: "the layers of the artifact are written into the destination directory, which is created if needed",
+	}
+}
 
 // Gather copies a file or directory from the source path to the destination path.
 // It returns the metadata of the gathered file or directory and any error encountered.
 // Portions of this file are derivative from the open-policy-agent/conftest project.
 func (f *OCIGatherer) Gather(ctx context.Context, source, destination string) (metadata.Metadata, error) {
+	if f.Offline {
+		return nil, fmt.Errorf("%w: cannot gather %s", gogather.ErrNetworkDisabled, source)
+	}
+
+	if err := gogather.ValidateDestination(destination); err != nil {
+		return nil, err
+	}
+
+	src, repo, err := repository(source, f.Transport)
+	if err != nil {
+		return nil, err
+	}
+
+	var tmpl *template.Template
+	if f.NameTemplate != "" {
+		if tmpl, err = template.New("name").Option("missingkey=error").Parse(f.NameTemplate); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidNameTemplate, err)
+		}
+	}
+
+	if f.ExpectedArtifactType != "" {
+		if err := f.checkArtifactType(ctx, src, repo); err != nil {
+			return nil, err
+		}
+	}
+
+	// Check the destination is empty, unless its content may be overwritten
+	entries, err := os.ReadDir(destination)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read destination directory: %w", err)
+	}
+	existed, empty := err == nil, len(entries) == 0
+	if !empty && !f.Overwrite {
+		return nil, fmt.Errorf("%w: %s", ErrDestinationNotEmpty, destination)
+	}
+
+	// Create the destination directory
+	dirMode := f.DirMode
+	if dirMode == 0 {
+		dirMode = gogather.DefaultDirMode
+	}
+	if err := os.MkdirAll(destination, dirMode); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	cache := f.Cache
+	if f.Platform != nil {
+		cache = nil
+	}
+	if cache != nil {
+		desc, err := src.Resolve(ctx, repo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", repo, err)
+		}
+		hit, err := cache.Get(desc.Digest.String(), destination)
+		if err != nil {
+			return nil, err
+		}
+		if hit {
+			return &oci.OCIMetadata{Digest: desc.Digest.String()}, nil
+		}
+	}
+
+	// Create the store the artifact is copied to. The file store writes layers with a title to the destination.
+	// Layers named by the NameTemplate, and untitled layers, are not copied to the store but streamed from the
+	// registry by writeLayers, so the memory store holds only the manifest.
+	var store oras.Target
+	name := func(layer ocispec.Descriptor) (string, error) {
+		return untitledLayerName(layer), nil
+	}
+	if tmpl != nil {
+		store = memory.New()
+		name = func(layer ocispec.Descriptor) (string, error) {
+			return renderLayerName(tmpl, layer)
+		}
+	} else {
+		fileStore, err := file.New(destination)
+		if err != nil {
+			return nil, fmt.Errorf("file store: %w", err)
+		}
+		defer fileStore.Close()
+		store = fileStore
+	}
+
+	opts := oras.DefaultCopyOptions
+	opts.WithTargetPlatform(f.Platform)
+	opts.PreCopy = func(ctx context.Context, desc ocispec.Descriptor) error {
+		if !isManifest(desc) && (tmpl != nil || desc.Annotations[ocispec.AnnotationTitle] == "") {
+			return oras.SkipNode
+		}
+		return nil
+	}
+
+	// Copy the artifact to the store
+	a, err := oras.Copy(ctx, src, repo, store, "", opts)
+	if err != nil {
+		// Don't leave a partially gathered artifact behind, unless it was gathered over existing content
+		if empty {
+			cleanup(destination, existed)
+		}
+		if errors.Is(err, file.ErrDuplicateName) || errors.Is(err, file.ErrPathTraversalDisallowed) {
+			return nil, fmt.Errorf("%w in %s: %w; set a NameTemplate to name the layers distinctly", ErrLayerTitleConflict, repo, err)
+		}
+		return nil, fmt.Errorf("pulling policy: %w", err)
+	}
+
+	if err := writeLayers(ctx, store, src, a, destination, tmpl == nil, name); err != nil {
+		if empty {
+			cleanup(destination, existed)
+		}
+		return nil, err
+	}
+
+	// Only cache what was pulled, not content the destination already had
+	if cache != nil && empty {
+		if err := cache.Put(a.Digest.String(), destination); err != nil {
+			return nil, fmt.Errorf("failed to cache artifact: %w", err)
+		}
+	}
+
+	return &oci.OCIMetadata{Digest: a.Digest.String()}, nil
+}
+
+// Head resolves the artifact at source without pulling it, and returns its metadata.
+func (f *OCIGatherer) Head(ctx context.Context, source string) (metadata.Metadata, error) {
+	if f.Offline {
+		return nil, fmt.Errorf("%w: cannot resolve %s", gogather.ErrNetworkDisabled, source)
+	}
+
+	src, repo, err := repository(source, f.Transport)
+	if err != nil {
+		return nil, err
+	}
+
+	desc, err := src.Resolve(ctx, repo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", repo, err)
+	}
+	return &oci.OCIMetadata{Digest: desc.Digest.String()}, nil
+}
+
+// Validate checks that source is a valid reference to an artifact, without resolving it.
+func (f *OCIGatherer) Validate(source string) error {
+	repo := ociURLParse(source)
+	if err := checkRepository(source, repo); err != nil {
+		return err
+	}
+	if _, err := registry.ParseReference(repo); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidReference, err)
+	}
+	return nil
+}
+
+// checkRepository returns ErrOCINoRepository if the reference repo, parsed from source, has no repository after
+// the registry.
+func checkRepository(source, repo string) error {
+	if _, path, _ := strings.Cut(repo, "/"); path == "" {
+		return fmt.Errorf("%w: %q", ErrOCINoRepository, source)
+	}
+	return nil
+}
+
+// repository returns the client for the repository of the artifact at source, sending its requests with the
+// transport, and the reference of the artifact, tagged "latest" if source has no tag or digest.
+func repository(source string, transport http.RoundTripper) (*remote.Repository, string, error) {
 	if strings.Contains(source, "localhost") {
 		source = strings.ReplaceAll(source, "localhost", "127.0.0.1")
 	}
 
 	// Parse the source URI
 	repo := ociURLParse(source)
+	if err := checkRepository(source, repo); err != nil {
+		return nil, "", err
+	}
 
 	// Get the artifact reference
 	ref, err := registry.ParseReference(repo)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse reference: %w", err)
+		return nil, "", fmt.Errorf("failed to parse reference: %w", err)
 	}
 
-	// If the reference is empty, set it to "latest"
+	// If the reference has neither a tag nor a digest, set it to "latest". A digest is pulled as is.
 	if ref.Reference == "" {
 		ref.Reference = "latest"
 		repo = ref.String()
@@ -66,33 +297,185 @@ func (f *OCIGatherer) Gather(ctx context.Context, source, destination string) (m
 	// Create the repository client
 	src, err := remote.NewRepository(repo)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create repository client: %w", err)
+		return nil, "", fmt.Errorf("failed to create repository client: %w", err)
 	}
 
 	// Setup the client for the repository
-	if err := r.SetupClient(src); err != nil {
-		return nil, fmt.Errorf("failed to setup repository client: %w", err)
+	if err := r.SetupClient(src, transport); err != nil {
+		return nil, "", fmt.Errorf("failed to setup repository client: %w", err)
 	}
+	return src, repo, nil
+}
 
-	// Create the destination directory
-	if err := os.MkdirAll(destination, os.ModePerm); err != nil {
-		return nil, fmt.Errorf("failed to create directory: %w", err)
+// isManifest reports whether desc describes a manifest or an index, rather than a blob
+func isManifest(desc ocispec.Descriptor) bool {
+	switch desc.MediaType {
+	case ocispec.MediaTypeImageManifest, ocispec.MediaTypeImageIndex,
+		"application/vnd.docker.distribution.manifest.v2+json", "application/vnd.docker.distribution.manifest.list.v2+json":
+		return true
 	}
+	return false
+}
 
-	// Create the file store
-	fileStore, err := file.New(destination)
+// writeLayers writes the layers of the manifest described by desc, fetched from store, to the destination directory,
+// streaming them from src. Each is written to the file named by name, or skipped if it has a title and untitledOnly
+// is set, as the file store has already written it. It fails with ErrInvalidNameTemplate if two layers have the
+// same name, or a name is not a file name.
+func writeLayers(ctx context.Context, store, src content.Fetcher, desc ocispec.Descriptor, destination string, untitledOnly bool, name func(ocispec.Descriptor) (string, error)) error {
+	if desc.MediaType != ocispec.MediaTypeImageManifest {
+		return nil
+	}
+
+	b, err := content.FetchAll(ctx, store, desc)
 	if err != nil {
-		return nil, fmt.Errorf("file store: %w", err)
+		return fmt.Errorf("failed to read manifest: %w", err)
+	}
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		return fmt.Errorf("failed to parse manifest: %w", err)
 	}
-	defer fileStore.Close()
 
-	// Copy the artifact to the file store
-	a, err := oras.Copy(ctx, src, repo, fileStore, "", oras.DefaultCopyOptions)
+	written := map[string]bool{}
+	for _, layer := range manifest.Layers {
+		if untitledOnly && layer.Annotations[ocispec.AnnotationTitle] != "" {
+			continue
+		}
+
+		n, err := name(layer)
+		if err != nil {
+			return err
+		}
+		if n == "" || n == "." || n == ".." || written[n] {
+			return fmt.Errorf("%w: layer %s would be written to %q", ErrInvalidNameTemplate, layer.Digest, n)
+		}
+		written[n] = true
+
+		if err := writeLayer(ctx, src, layer, filepath.Join(destination, n)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeLayer streams the layer from src to the file at path, verifying its size and digest
+func writeLayer(ctx context.Context, src content.Fetcher, layer ocispec.Descriptor, path string) (err error) {
+	rc, err := src.Fetch(ctx, layer)
 	if err != nil {
-		return nil, fmt.Errorf("pulling policy: %w", err)
+		return fmt.Errorf("failed to read layer %s: %w", layer.Digest, err)
 	}
+	defer rc.Close()
 
-	return &oci.OCIMetadata{Digest: a.Digest.String()}, nil
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write layer %s: %w", layer.Digest, err)
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil && cerr != nil {
+			err = fmt.Errorf("failed to write layer %s: %w", layer.Digest, cerr)
+		}
+	}()
+
+	vr := content.NewVerifyReader(rc, layer)
+	if _, err := io.Copy(f, vr); err != nil {
+		return fmt.Errorf("failed to write layer %s: %w", layer.Digest, err)
+	}
+	if err := vr.Verify(); err != nil {
+		return fmt.Errorf("failed to verify layer %s: %w", layer.Digest, err)
+	}
+	return nil
+}
+
+// LayerName is the data a NameTemplate is rendered with for each layer.
+type LayerName struct {
+	// Title is the title annotation of the layer, which may be empty.
+	Title string
+	// Digest is the digest of the layer without its algorithm, e.g. "0123...".
+	Digest string
+	// MediaType is the media type of the layer.
+	MediaType string
+	// Name is the name the layer is written to without a NameTemplate: its title, or a name derived
+	// from its digest if it has none.
+	Name string
+}
+
+// renderLayerName renders tmpl for layer, replacing path separators in the result with underscores.
+func renderLayerName(tmpl *template.Template, layer ocispec.Descriptor) (string, error) {
+	data := LayerName{
+		Title:     layer.Annotations[ocispec.AnnotationTitle],
+		Digest:    layer.Digest.Encoded(),
+		MediaType: layer.MediaType,
+		Name:      layer.Annotations[ocispec.AnnotationTitle],
+	}
+	if data.Name == "" {
+		data.Name = untitledLayerName(layer)
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidNameTemplate, err)
+	}
+	return strings.NewReplacer("/", "_", "\\", "_").Replace(b.String()), nil
+}
+
+// untitledLayerName returns the name of the file an untitled layer is written to: "blob-" followed by the first
+// 12 characters of its digest, and an extension derived from its media type, e.g. "blob-0123456789ab.tar.gz".
+func untitledLayerName(layer ocispec.Descriptor) string {
+	name := "blob-" + layer.Digest.Encoded()
+	if len(name) > len("blob-")+12 {
+		name = name[:len("blob-")+12]
+	}
+
+	mediaType, suffix, _ := strings.Cut(layer.MediaType, "+")
+	switch {
+	case strings.HasSuffix(mediaType, ".tar") || strings.HasSuffix(mediaType, "/x-tar"):
+		name += ".tar"
+	case strings.HasSuffix(mediaType, "/json") || suffix == "json":
+		return name + ".json"
+	}
+
+	switch suffix {
+	case "gzip":
+		name += ".gz"
+	case "zstd":
+		name += ".zst"
+	}
+	return name
+}
+
+// checkArtifactType returns ErrArtifactTypeMismatch if the manifest of the artifact at ref is not of the ExpectedArtifactType
+func (f *OCIGatherer) checkArtifactType(ctx context.Context, src *remote.Repository, ref string) error {
+	opts := oras.DefaultFetchBytesOptions
+	opts.TargetPlatform = f.Platform
+	_, content, err := oras.FetchBytes(ctx, src, ref, opts)
+	if err != nil {
+		return fmt.Errorf("failed to fetch manifest: %w", err)
+	}
+
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	artifactType := manifest.ArtifactType
+	if artifactType == "" {
+		artifactType = manifest.Config.MediaType
+	}
+	if artifactType != f.ExpectedArtifactType {
+		return fmt.Errorf("%w: expected %s, got %q", ErrArtifactTypeMismatch, f.ExpectedArtifactType, artifactType)
+	}
+	return nil
+}
+
+// cleanup removes the content of the destination directory, and the directory itself if it did not exist before.
+func cleanup(destination string, existed bool) {
+	if !existed {
+		_ = os.RemoveAll(destination)
+		return
+	}
+	entries, _ := os.ReadDir(destination)
+	for _, entry := range entries {
+		_ = os.RemoveAll(filepath.Join(destination, entry.Name()))
+	}
 }
 
 func ociURLParse(source string) string {
@@ -2,10 +2,23 @@ package oci
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/content"
+
+	gogather "github.com/enterprise-contract/go-gather"
 )
 
 func getRegistryURL(src string) string {
@@ -120,19 +133,19 @@ func TestOCIGatherer_Gather_Invalid_URIs(t *testing.T) {
 			name:        "Invalid source URI",
 			source:      "invalid",
 			destination: "/tmp/foo",
-			expectedErr: fmt.Errorf("failed to parse reference: invalid reference: missing registry or repository"),
+			expectedErr: fmt.Errorf("no repository in OCI reference: %q", "invalid"),
 		},
 		{
 			name:        "Invalid source URI with tag",
 			source:      "invalid:tag",
 			destination: "/tmp/foo",
-			expectedErr: fmt.Errorf("failed to parse reference: invalid reference: missing registry or repository"),
+			expectedErr: fmt.Errorf("no repository in OCI reference: %q", "invalid:tag"),
 		},
 		{
 			name:        "Invalid source URI with HTTPS",
 			source:      "https://invalid",
 			destination: "/tmp/foo",
-			expectedErr: fmt.Errorf("failed to parse reference: invalid reference: missing registry or repository"),
+			expectedErr: fmt.Errorf("no repository in OCI reference: %q", "https://invalid"),
 		},
 	}
 
@@ -156,6 +169,19 @@ func TestOCIGatherer_Gather_Invalid_URIs(t *testing.T) {
 	}
 }
 
+// TestOCIGatherer_Gather_NoRepository tests that a source without a repository fails with ErrOCINoRepository.
+func TestOCIGatherer_Gather_NoRepository(t *testing.T) {
+	for _, source := range []string{"oci://", "oci::", "oci://registry.example.com", "oci://registry.example.com/"} {
+		_, err := (&OCIGatherer{}).Gather(context.Background(), source, t.TempDir())
+		if !errors.Is(err, ErrOCINoRepository) {
+			t.Errorf("Expected Gather(%q) to return %v, but got %v", source, ErrOCINoRepository, err)
+		}
+		if err := (&OCIGatherer{}).Validate(source); !errors.Is(err, ErrOCINoRepository) {
+			t.Errorf("Expected Validate(%q) to return %v, but got %v", source, ErrOCINoRepository, err)
+		}
+	}
+}
+
 // TestOCIGatherer_Gather_ErorrCreatingNewRepository tests the Gather function with an error creating a new repository client.
 func TestOCIGatherer_Gather_ErorrCreatingNewRepository(t *testing.T) {
 	testCases := []struct {
@@ -168,7 +194,7 @@ func TestOCIGatherer_Gather_ErorrCreatingNewRepository(t *testing.T) {
 			name:        "Error creating new repository",
 			source:      "docker.io",
 			destination: "/tmp/foo",
-			expectedErr: fmt.Errorf("failed to parse reference: invalid reference: missing registry or repository"),
+			expectedErr: fmt.Errorf("no repository in OCI reference: %q", "docker.io"),
 		},
 	}
 	for _, tc := range testCases {
@@ -192,3 +218,630 @@ func TestOCIGatherer_Gather_ErorrCreatingNewRepository(t *testing.T) {
 	}
 
 }
+
+// TestOCIGatherer_Gather_Offline tests that Gather fails without contacting the registry when offline.
+func TestOCIGatherer_Gather_Offline(t *testing.T) {
+	destination := t.TempDir() + "/out"
+	gatherer := &OCIGatherer{Offline: true}
+	_, err := gatherer.Gather(context.Background(), "quay.io/libpod/alpine:latest", destination)
+	if !errors.Is(err, gogather.ErrNetworkDisabled) {
+		t.Errorf("Expected ErrNetworkDisabled, but got %v", err)
+	}
+	if _, err := os.Stat(destination); !os.IsNotExist(err) {
+		t.Errorf("Expected the destination not to be created, but got %v", err)
+	}
+}
+
+// TestOCIGatherer_Describe tests the Describe function.
+func TestOCIGatherer_Describe(t *testing.T) {
+	info := (&OCIGatherer{}).Describe()
+	if info.Name != "oci" {
+		t.Errorf("Expected name oci, but got %s", info.Name)
+	}
+	if info.Extracts {
+		t.Error("Expected the OCI gatherer not to extract archives")
+	}
+	if len(info.Prefixes) != 2 || info.Prefixes[0] != "oci::" {
+		t.Errorf("Unexpected prefixes: %v", info.Prefixes)
+	}
+}
+
+// testLayer is a file layer served by the test registry.
+type testLayer struct {
+	Title   string
+	Content string
+	// Corrupt serves content that does not match the digest of the layer
+	Corrupt bool
+}
+
+// newTestRegistry starts a registry serving a single artifact made of the given layers and returns its reference.
+func newTestRegistry(t *testing.T, layers []testLayer) string {
+	t.Helper()
+	return newTestArtifactRegistry(t, "", layers)
+}
+
+// newTestArtifactRegistry is like newTestRegistry, but the manifest of the artifact has the given artifactType, if any.
+func newTestArtifactRegistry(t *testing.T, artifactType string, layers []testLayer) string {
+	t.Helper()
+
+	digest := func(b []byte) string {
+		return fmt.Sprintf("sha256:%x", sha256.Sum256(b))
+	}
+
+	blobs := map[string][]byte{}
+	config := []byte("{}")
+	blobs[digest(config)] = config
+
+	descriptors := []map[string]any{}
+	for _, l := range layers {
+		d := digest([]byte(l.Content))
+		blobs[d] = []byte(l.Content)
+		if l.Corrupt {
+			blobs[d] = []byte(strings.ToUpper(l.Content))
+		}
+		descriptor := map[string]any{
+			"mediaType": "application/vnd.oci.image.layer.v1.tar",
+			"digest":    d,
+			"size":      len(l.Content),
+		}
+		if l.Title != "" {
+			descriptor["annotations"] = map[string]string{"org.opencontainers.image.title": l.Title}
+		}
+		descriptors = append(descriptors, descriptor)
+	}
+
+	m := map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     "application/vnd.oci.image.manifest.v1+json",
+		"config": map[string]any{
+			"mediaType": "application/vnd.oci.empty.v1+json",
+			"digest":    digest(config),
+			"size":      len(config),
+		},
+		"layers": descriptors,
+	}
+	if artifactType != "" {
+		m["artifactType"] = artifactType
+	}
+	manifest, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var content []byte
+		switch {
+		case r.URL.Path == "/v2/":
+			return
+		case strings.HasPrefix(r.URL.Path, "/v2/repo/manifests/"):
+			content = manifest
+			w.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+			w.Header().Set("Docker-Content-Digest", digest(manifest))
+		case strings.HasPrefix(r.URL.Path, "/v2/repo/blobs/"):
+			blob, ok := blobs[strings.TrimPrefix(r.URL.Path, "/v2/repo/blobs/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			content = blob
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+		if r.Method != http.MethodHead {
+			_, _ = w.Write(content)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return strings.TrimPrefix(server.URL, "http://") + "/repo:latest"
+}
+
+// TestOCIGatherer_Gather_DestinationNotEmpty tests gathering into a destination that already has content.
+func TestOCIGatherer_Gather_DestinationNotEmpty(t *testing.T) {
+	ref := newTestRegistry(t, []testLayer{{Title: "policy.rego", Content: "package main"}})
+
+	populate := func(t *testing.T) string {
+		destination := t.TempDir()
+		if err := os.WriteFile(filepath.Join(destination, "policy.rego"), []byte("existing"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return destination
+	}
+
+	t.Run("empty destination", func(t *testing.T) {
+		destination := filepath.Join(t.TempDir(), "out")
+		gatherer := &OCIGatherer{}
+		if _, err := gatherer.Gather(context.Background(), ref, destination); err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+		content, err := os.ReadFile(filepath.Join(destination, "policy.rego"))
+		if err != nil || string(content) != "package main" {
+			t.Errorf("Expected the artifact to be gathered, but got %q, %v", content, err)
+		}
+	})
+
+	t.Run("not empty", func(t *testing.T) {
+		destination := populate(t)
+		gatherer := &OCIGatherer{}
+		_, err := gatherer.Gather(context.Background(), ref, destination)
+		if !errors.Is(err, ErrDestinationNotEmpty) {
+			t.Errorf("Expected ErrDestinationNotEmpty, but got %v", err)
+		}
+		content, _ := os.ReadFile(filepath.Join(destination, "policy.rego"))
+		if string(content) != "existing" {
+			t.Errorf("Expected the existing file to be kept, but got %q", content)
+		}
+	})
+
+	t.Run("overwrite", func(t *testing.T) {
+		destination := populate(t)
+		gatherer := &OCIGatherer{Overwrite: true}
+		if _, err := gatherer.Gather(context.Background(), ref, destination); err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+		content, _ := os.ReadFile(filepath.Join(destination, "policy.rego"))
+		if string(content) != "package main" {
+			t.Errorf("Expected the existing file to be overwritten, but got %q", content)
+		}
+	})
+
+	t.Run("cleanup on error", func(t *testing.T) {
+		ref := newTestRegistry(t, []testLayer{
+			{Title: "policy.rego", Content: "package main"},
+			{Title: "data.txt", Content: "data", Corrupt: true},
+		})
+		destination := filepath.Join(t.TempDir(), "out")
+		gatherer := &OCIGatherer{}
+		if _, err := gatherer.Gather(context.Background(), ref, destination); err == nil {
+			t.Fatal("Expected an error, but got nil")
+		}
+		if _, err := os.Stat(destination); !os.IsNotExist(err) {
+			t.Errorf("Expected the destination to be removed, but got %v", err)
+		}
+	})
+}
+
+// newTestIndexRegistry starts a registry serving an image index with a manifest for each of the given platforms,
+// each with a platform.txt layer containing the platform. It returns the reference of the index and the
+// digests of the manifests by platform.
+func newTestIndexRegistry(t *testing.T, platforms []ocispec.Platform) (string, map[string]string) {
+	t.Helper()
+
+	digest := func(b []byte) string {
+		return fmt.Sprintf("sha256:%x", sha256.Sum256(b))
+	}
+
+	blobs := map[string][]byte{}
+	manifests := map[string][]byte{}
+	digests := map[string]string{}
+	descriptors := []map[string]any{}
+
+	for _, p := range platforms {
+		name := p.OS + "/" + p.Architecture
+
+		config, err := json.Marshal(map[string]string{"os": p.OS, "architecture": p.Architecture})
+		if err != nil {
+			t.Fatal(err)
+		}
+		blobs[digest(config)] = config
+		layer := []byte(name)
+		blobs[digest(layer)] = layer
+
+		manifest, err := json.Marshal(map[string]any{
+			"schemaVersion": 2,
+			"mediaType":     "application/vnd.oci.image.manifest.v1+json",
+			"config": map[string]any{
+				"mediaType": "application/vnd.oci.image.config.v1+json",
+				"digest":    digest(config),
+				"size":      len(config),
+			},
+			"layers": []map[string]any{{
+				"mediaType":   "application/vnd.oci.image.layer.v1.tar",
+				"digest":      digest(layer),
+				"size":        len(layer),
+				"annotations": map[string]string{"org.opencontainers.image.title": "platform.txt"},
+			}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		manifests[digest(manifest)] = manifest
+		digests[name] = digest(manifest)
+
+		descriptors = append(descriptors, map[string]any{
+			"mediaType": "application/vnd.oci.image.manifest.v1+json",
+			"digest":    digest(manifest),
+			"size":      len(manifest),
+			"platform":  map[string]string{"os": p.OS, "architecture": p.Architecture},
+		})
+	}
+
+	index, err := json.Marshal(map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     "application/vnd.oci.image.index.v1+json",
+		"manifests":     descriptors,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var content []byte
+		switch {
+		case r.URL.Path == "/v2/":
+			return
+		case r.URL.Path == "/v2/repo/manifests/latest":
+			content = index
+			w.Header().Set("Content-Type", "application/vnd.oci.image.index.v1+json")
+			w.Header().Set("Docker-Content-Digest", digest(index))
+		case strings.HasPrefix(r.URL.Path, "/v2/repo/manifests/"):
+			manifest, ok := manifests[strings.TrimPrefix(r.URL.Path, "/v2/repo/manifests/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			content = manifest
+			w.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+			w.Header().Set("Docker-Content-Digest", digest(manifest))
+		case strings.HasPrefix(r.URL.Path, "/v2/repo/blobs/"):
+			blob, ok := blobs[strings.TrimPrefix(r.URL.Path, "/v2/repo/blobs/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			content = blob
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+		if r.Method != http.MethodHead {
+			_, _ = w.Write(content)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return strings.TrimPrefix(server.URL, "http://") + "/repo:latest", digests
+}
+
+// TestOCIGatherer_Gather_Platform tests gathering the manifest for a platform from an image index.
+func TestOCIGatherer_Gather_Platform(t *testing.T) {
+	ref, digests := newTestIndexRegistry(t, []ocispec.Platform{
+		{OS: "linux", Architecture: "amd64"},
+		{OS: "linux", Architecture: "arm64"},
+	})
+
+	for _, platform := range []string{"linux/amd64", "linux/arm64"} {
+		t.Run(platform, func(t *testing.T) {
+			goos, goarch, _ := strings.Cut(platform, "/")
+			destination := filepath.Join(t.TempDir(), "out")
+			gatherer := &OCIGatherer{Platform: &ocispec.Platform{OS: goos, Architecture: goarch}}
+			m, err := gatherer.Gather(context.Background(), ref, destination)
+			if err != nil {
+				t.Fatalf("Expected no error, but got %v", err)
+			}
+
+			if digest := m.Get()["digest"]; digest != digests[platform] {
+				t.Errorf("Expected the digest of the %s manifest %s, but got %s", platform, digests[platform], digest)
+			}
+
+			content, err := os.ReadFile(filepath.Join(destination, "platform.txt"))
+			if err != nil || string(content) != platform {
+				t.Errorf("Expected the %s manifest to be gathered, but got %q, %v", platform, content, err)
+			}
+		})
+	}
+}
+
+// TestOCIGatherer_Gather_ExpectedArtifactType tests that only artifacts of the expected type are gathered.
+func TestOCIGatherer_Gather_ExpectedArtifactType(t *testing.T) {
+	const policyType = "application/vnd.example.policy.v1"
+	layers := []testLayer{{Title: "policy.rego", Content: "package main"}}
+	policy := newTestArtifactRegistry(t, policyType, layers)
+	data := newTestArtifactRegistry(t, "application/vnd.example.data.v1", layers)
+	untyped := newTestRegistry(t, layers)
+
+	testCases := []struct {
+		name     string
+		ref      string
+		expected string
+		err      error
+	}{
+		{name: "matching type", ref: policy, expected: policyType},
+		{name: "other type", ref: data, expected: policyType, err: ErrArtifactTypeMismatch},
+		{name: "config media type", ref: untyped, expected: "application/vnd.oci.empty.v1+json"},
+		{name: "untyped", ref: untyped, expected: policyType, err: ErrArtifactTypeMismatch},
+		{name: "not checked", ref: data},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			destination := filepath.Join(t.TempDir(), "out")
+			gatherer := &OCIGatherer{ExpectedArtifactType: tc.expected}
+			_, err := gatherer.Gather(context.Background(), tc.ref, destination)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("Expected error %v, but got %v", tc.err, err)
+			}
+			_, statErr := os.Stat(filepath.Join(destination, "policy.rego"))
+			if tc.err == nil && statErr != nil {
+				t.Errorf("Expected the artifact to be gathered, but got %v", statErr)
+			}
+			if tc.err != nil && statErr == nil {
+				t.Error("Expected the artifact not to be gathered")
+			}
+		})
+	}
+}
+
+// TestOCIGatherer_Gather_UntitledLayer tests that layers without a title are written under a name derived from their digest.
+func TestOCIGatherer_Gather_UntitledLayer(t *testing.T) {
+	ref := newTestRegistry(t, []testLayer{
+		{Title: "policy.rego", Content: "package main"},
+		{Content: "untitled"},
+	})
+
+	destination := filepath.Join(t.TempDir(), "out")
+	if _, err := (&OCIGatherer{}).Gather(context.Background(), ref, destination); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	name := fmt.Sprintf("blob-%x", sha256.Sum256([]byte("untitled")))[:len("blob-")+12] + ".tar"
+	content, err := os.ReadFile(filepath.Join(destination, name))
+	if err != nil || string(content) != "untitled" {
+		t.Errorf("Expected the untitled layer to be written to %s, but got %q, %v", name, content, err)
+	}
+	if _, err := os.Stat(filepath.Join(destination, "policy.rego")); err != nil {
+		t.Errorf("Expected the titled layer to be written, but got %v", err)
+	}
+}
+
+// TestUntitledLayerName tests the names of the files untitled layers are written to.
+func TestUntitledLayerName(t *testing.T) {
+	const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	testCases := []struct {
+		mediaType string
+		expected  string
+	}{
+		{mediaType: "application/vnd.oci.image.layer.v1.tar", expected: "blob-0123456789ab.tar"},
+		{mediaType: "application/vnd.oci.image.layer.v1.tar+gzip", expected: "blob-0123456789ab.tar.gz"},
+		{mediaType: "application/vnd.oci.image.layer.v1.tar+zstd", expected: "blob-0123456789ab.tar.zst"},
+		{mediaType: "application/vnd.example.config.v1+json", expected: "blob-0123456789ab.json"},
+		{mediaType: "application/octet-stream", expected: "blob-0123456789ab"},
+	}
+
+	for _, tc := range testCases {
+		layer := ocispec.Descriptor{MediaType: tc.mediaType, Digest: digest}
+		if name := untitledLayerName(layer); name != tc.expected {
+			t.Errorf("Expected untitledLayerName for %s to return %s, but got %s", tc.mediaType, tc.expected, name)
+		}
+	}
+}
+
+// TestOCIGatherer_Head tests resolving the digest of an artifact without pulling it.
+func TestOCIGatherer_Head(t *testing.T) {
+	ref := newTestRegistry(t, []testLayer{{Title: "policy.rego", Content: "package main"}})
+
+	m, err := (&OCIGatherer{}).Head(context.Background(), ref)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if digest := m.Get()["digest"].(string); !strings.HasPrefix(digest, "sha256:") {
+		t.Errorf("Expected a sha256 digest, but got %q", digest)
+	}
+
+	if _, err := (&OCIGatherer{Offline: true}).Head(context.Background(), ref); !errors.Is(err, gogather.ErrNetworkDisabled) {
+		t.Errorf("Expected ErrNetworkDisabled, but got %v", err)
+	}
+}
+
+// TestOCIGatherer_Gather_Cache tests that a cached artifact is copied from the cache.
+func TestOCIGatherer_Gather_Cache(t *testing.T) {
+	ref := newTestRegistry(t, []testLayer{{Title: "policy.rego", Content: "package main"}})
+	cache := &gogather.DirCache{Dir: t.TempDir()}
+	gatherer := &OCIGatherer{Cache: cache}
+
+	m, err := gatherer.Gather(context.Background(), ref, filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	digest := m.Get()["digest"].(string)
+
+	// Replace the cached content, to tell a copy from the cache from a pull
+	cached := t.TempDir()
+	if err := os.WriteFile(filepath.Join(cached, "policy.rego"), []byte("package cached"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Put(digest, cached); err != nil {
+		t.Fatal(err)
+	}
+
+	destination := filepath.Join(t.TempDir(), "out")
+	if _, err := gatherer.Gather(context.Background(), ref, destination); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(destination, "policy.rego"))
+	if err != nil || string(content) != "package cached" {
+		t.Errorf("Expected the artifact to be copied from the cache, but got %q, %v", content, err)
+	}
+}
+
+// TestOCIGatherer_Gather_NameTemplate tests that layers are written to files named by the NameTemplate.
+func TestOCIGatherer_Gather_NameTemplate(t *testing.T) {
+	ref := newTestRegistry(t, []testLayer{
+		{Title: "policy.rego", Content: "package main"},
+		{Content: "untitled"},
+	})
+	short := func(content string) string {
+		return fmt.Sprintf("%x", sha256.Sum256([]byte(content)))[:8]
+	}
+
+	testCases := []struct {
+		name     string
+		template string
+		expected map[string]string
+		err      error
+	}{
+		{
+			name:     "title or digest",
+			template: `{{if .Title}}{{.Title}}{{else}}{{slice .Digest 0 8}}.bin{{end}}`,
+			expected: map[string]string{"policy.rego": "package main", short("untitled") + ".bin": "untitled"},
+		},
+		{
+			name:     "path separators",
+			template: `layers/{{slice .Digest 0 8}}`,
+			expected: map[string]string{"layers_" + short("package main"): "package main", "layers_" + short("untitled"): "untitled"},
+		},
+		{name: "collision", template: `layer`, err: ErrInvalidNameTemplate},
+		{name: "unknown field", template: `{{.Size}}`, err: ErrInvalidNameTemplate},
+		{name: "unparsable", template: `{{`, err: ErrInvalidNameTemplate},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			destination := filepath.Join(t.TempDir(), "out")
+			_, err := (&OCIGatherer{NameTemplate: tc.template}).Gather(context.Background(), ref, destination)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("Expected error %v, but got %v", tc.err, err)
+			}
+
+			for name, expected := range tc.expected {
+				content, err := os.ReadFile(filepath.Join(destination, name))
+				if err != nil || string(content) != expected {
+					t.Errorf("Expected %s to hold %q, but got %q, %v", name, expected, content, err)
+				}
+			}
+		})
+	}
+}
+
+// TestOCIGatherer_Gather_NameTemplate_Corrupt tests that a layer streamed to the file named by the NameTemplate is verified.
+func TestOCIGatherer_Gather_NameTemplate_Corrupt(t *testing.T) {
+	ref := newTestRegistry(t, []testLayer{
+		{Title: "policy.rego", Content: "package main"},
+		{Title: "data.txt", Content: "data", Corrupt: true},
+	})
+	destination := filepath.Join(t.TempDir(), "out")
+	_, err := (&OCIGatherer{NameTemplate: "{{.Name}}"}).Gather(context.Background(), ref, destination)
+	if !errors.Is(err, content.ErrMismatchedDigest) {
+		t.Fatalf("Expected error %v, but got %v", content.ErrMismatchedDigest, err)
+	}
+	if _, err := os.Stat(destination); !os.IsNotExist(err) {
+		t.Errorf("Expected the destination to be removed, but got %v", err)
+	}
+}
+
+// TestOCIGatherer_Gather_LayerTitleConflict tests gathering an artifact with layers that cannot be written to their titles.
+func TestOCIGatherer_Gather_LayerTitleConflict(t *testing.T) {
+	testCases := []struct {
+		name   string
+		layers []testLayer
+	}{
+		{name: "duplicate title", layers: []testLayer{{Title: "policy.rego", Content: "package a"}, {Title: "policy.rego", Content: "package b"}}},
+		{name: "path traversal", layers: []testLayer{{Title: "../policy.rego", Content: "package main"}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ref := newTestRegistry(t, tc.layers)
+			destination := filepath.Join(t.TempDir(), "out")
+
+			_, err := (&OCIGatherer{}).Gather(context.Background(), ref, destination)
+			if !errors.Is(err, ErrLayerTitleConflict) {
+				t.Fatalf("Expected error %v, but got %v", ErrLayerTitleConflict, err)
+			}
+			if !strings.Contains(err.Error(), "NameTemplate") {
+				t.Errorf("Expected the error to suggest a NameTemplate, but got %v", err)
+			}
+			if _, err := os.Stat(destination); !os.IsNotExist(err) {
+				t.Errorf("Expected the destination to be removed, but got %v", err)
+			}
+
+			// Named by a template, the layers are gathered
+			if _, err := (&OCIGatherer{NameTemplate: "{{.Digest}}"}).Gather(context.Background(), ref, destination); err != nil {
+				t.Errorf("Expected no error with a NameTemplate, but got %v", err)
+			}
+		})
+	}
+}
+
+// TestOCIGatherer_Gather_DirMode tests the mode of the destination directory created by Gather.
+func TestOCIGatherer_Gather_DirMode(t *testing.T) {
+	ref := newTestRegistry(t, []testLayer{{Title: "policy.rego", Content: "package main"}})
+
+	for _, mode := range []os.FileMode{0, 0700} {
+		expected := mode
+		if expected == 0 {
+			expected = gogather.DefaultDirMode
+		}
+
+		destination := filepath.Join(t.TempDir(), "out")
+		if _, err := (&OCIGatherer{DirMode: mode}).Gather(context.Background(), ref, destination); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		info, err := os.Stat(destination)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != expected {
+			t.Errorf("Expected the destination to have mode %v, but got %v", expected, info.Mode().Perm())
+		}
+	}
+}
+
+// TestOCIGatherer_Gather_Digest tests gathering an artifact referenced by its digest, without a tag.
+func TestOCIGatherer_Gather_Digest(t *testing.T) {
+	ref := newTestRegistry(t, []testLayer{{Title: "policy.rego", Content: "package main"}})
+
+	m, err := (&OCIGatherer{}).Head(context.Background(), ref)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	digest := m.Get()["digest"].(string)
+	source := strings.TrimSuffix(ref, ":latest") + "@" + digest
+
+	// The digest is not replaced by the "latest" tag
+	if _, repo, err := repository(source, nil); err != nil || repo != strings.Replace(source, "localhost", "127.0.0.1", 1) {
+		t.Fatalf("Expected the reference %s, but got %s, %v", source, repo, err)
+	}
+
+	destination := filepath.Join(t.TempDir(), "out")
+	m, err = (&OCIGatherer{}).Gather(context.Background(), "oci://"+source, destination)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if m.Get()["digest"] != digest {
+		t.Errorf("Expected digest %s, but got %s", digest, m.Get()["digest"])
+	}
+	if content, err := os.ReadFile(filepath.Join(destination, "policy.rego")); err != nil || string(content) != "package main" {
+		t.Errorf("Expected policy.rego to be gathered, but got %q, %v", content, err)
+	}
+}
+
+// TestOCIGatherer_Gather_Transport tests that gathers sharing a Transport reuse its connection to the registry.
+func TestOCIGatherer_Gather_Transport(t *testing.T) {
+	ref := newTestRegistry(t, []testLayer{{Title: "policy.rego", Content: "package main"}})
+
+	var dials atomic.Int32
+	dialer := &net.Dialer{}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dials.Add(1)
+		return dialer.DialContext(ctx, network, addr)
+	}
+	// Blobs are fetched concurrently, so allow a single connection for the count to be deterministic
+	transport.MaxConnsPerHost = 1
+	t.Cleanup(transport.CloseIdleConnections)
+
+	for i := 0; i < 2; i++ {
+		g := &OCIGatherer{Transport: transport}
+		if _, err := g.Gather(context.Background(), ref, filepath.Join(t.TempDir(), "out")); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	if n := dials.Load(); n != 1 {
+		t.Errorf("Expected the connection to be reused, but %d connections were made", n)
+	}
+}
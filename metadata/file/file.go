@@ -44,6 +44,7 @@ import (
 	"time"
 )
 
+// FileMetadata describes a gathered file. Size is the size of the file in bytes.
 type FileMetadata struct {
 	Size      int64
 	Path      string
@@ -51,10 +52,15 @@ type FileMetadata struct {
 	SHA       string
 }
 
+// DirectoryMetadata describes a gathered directory, including one an archive was expanded into.
+// Size is the total size in bytes of the FileCount regular files within it, recursively, and SHA
+// is the digest of their relative paths and content.
 type DirectoryMetadata struct {
 	Size      int64
+	FileCount int
 	Path      string
 	Timestamp time.Time
+	SHA       string
 }
 
 func (m *FileMetadata) Get() map[string]any {
@@ -68,8 +74,10 @@ func (m *FileMetadata) Get() map[string]any {
 
 func (m *DirectoryMetadata) Get() map[string]any {
 	return map[string]any{
-		"size":      m.Size,
-		"path":      m.Path,
-		"timestamp": m.Timestamp,
+		"size":       m.Size,
+		"file_count": m.FileCount,
+		"path":       m.Path,
+		"timestamp":  m.Timestamp,
+		"sha":        m.SHA,
 	}
 }
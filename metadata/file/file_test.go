@@ -57,8 +57,10 @@ func TestDirectoryMetadata_Get(t *testing.T) {
 	// Create a FileMetadata instance
 	m := &DirectoryMetadata{
 		Size:      int64(100),
+		FileCount: 3,
 		Path:      "/path/to/dir/",
 		Timestamp: testTime,
+		SHA:       "d2a84f4b8b650937ec8f73cd8be2c74add5a911ba64df27458ed8229da804a26",
 	}
 
 	// Call the Get method
@@ -66,9 +68,11 @@ func TestDirectoryMetadata_Get(t *testing.T) {
 
 	// Assert the expected values
 	expected := map[string]interface{}{
-		"size":      int64(100),
-		"path":      "/path/to/dir/",
-		"timestamp": testTime,
+		"size":       int64(100),
+		"file_count": 3,
+		"path":       "/path/to/dir/",
+		"timestamp":  testTime,
+		"sha":        "d2a84f4b8b650937ec8f73cd8be2c74add5a911ba64df27458ed8229da804a26",
 	}
 
 	if len(result) != len(expected) {
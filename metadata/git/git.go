@@ -42,12 +42,14 @@ import (
 )
 
 // GitMetadata is a struct that represents the metadata of a git repository.
-// It has fields for size, path, timestamp, and commits.
+// It has fields for size, path, timestamp, commits, and the ref that was cloned.
 type GitMetadata struct {
 	Size      int64
 	Path      string
 	Timestamp time.Time
 	Commits   []object.Commit
+	// Ref is the full name of the branch or tag that was cloned, e.g. "refs/heads/main" or "refs/tags/v1.0.0".
+	Ref string
 }
 
 func (m GitMetadata) Get() map[string]any {
@@ -56,6 +58,7 @@ func (m GitMetadata) Get() map[string]any {
 		"path":      m.Path,
 		"timestamp": m.Timestamp,
 		"commits":   m.Commits,
+		"ref":       m.Ref,
 	}
 }
 
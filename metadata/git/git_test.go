@@ -35,6 +35,7 @@ func TestGitMetadata_Get(t *testing.T) {
 			{Hash: plumbing.ComputeHash(plumbing.AnyObject, []byte("hash2"))},
 			{Hash: plumbing.ComputeHash(plumbing.AnyObject, []byte("hash3"))},
 		},
+		Ref: "refs/heads/main",
 	}
 
 	expectedResult := map[string]any{
@@ -42,6 +43,7 @@ func TestGitMetadata_Get(t *testing.T) {
 		"path":      "/path/to/repo",
 		"timestamp": metadata.Timestamp,
 		"commits":   metadata.Commits,
+		"ref":       "refs/heads/main",
 	}
 
 	defer os.RemoveAll(metadata.Path)
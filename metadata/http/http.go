@@ -16,11 +16,14 @@
 
 package http
 
+// HTTPMetadata describes a downloaded file. FinalURI is the URL it was downloaded from, after
+// following any redirects, which may be that of a mirror of the source.
 type HTTPMetadata struct {
 	StatusCode    int
 	ContentLength int64
 	Destination   string
 	Headers       map[string][]string
+	FinalURI      string
 }
 
 func (m HTTPMetadata) Get() map[string]any {
@@ -29,5 +32,6 @@ func (m HTTPMetadata) Get() map[string]any {
 		"contentLength": m.ContentLength,
 		"destination":   m.Destination,
 		"headers":       m.Headers,
+		"finalURI":      m.FinalURI,
 	}
 }
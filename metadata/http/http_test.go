@@ -12,6 +12,7 @@ func TestHTTPMetadata_Get(t *testing.T) {
 		ContentLength: 1024,
 		Destination:   "https://example.com",
 		Headers:       map[string][]string{"Content-Type": {"text/plain"}},
+		FinalURI:      "https://mirror.example.com/file.txt",
 	}
 
 	// Call the Get method
@@ -23,6 +24,7 @@ func TestHTTPMetadata_Get(t *testing.T) {
 		"contentLength": int64(1024),
 		"destination":   "https://example.com",
 		"headers":       map[string][]string{"Content-Type": {"text/plain"}},
+		"finalURI":      "https://mirror.example.com/file.txt",
 	}
 
 	if !reflect.DeepEqual(result, expected) {
@@ -30,18 +30,30 @@
 package file
 
 import (
+	"compress/gzip"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
+// ErrUnsupportedCompression is returned when Compress names a compression format that is not supported.
+var ErrUnsupportedCompression = errors.New("unsupported compression")
+
 // FileSaver handles saving data to local filesystem paths.
-type FileSaver struct{}
+type FileSaver struct {
+	// Compress is the format the data is compressed with while it is saved. The only supported format is "gzip",
+	// which adds a ".gz" extension to the destination unless it already has one. If empty, the data is saved as is.
+	Compress string
+}
 
-// Save implements the Saver interface for file destinations.
+// Save implements the Saver interface for file destinations. The data is written to a PartFile, which replaces
+// the destination once complete, so concurrent saves into the same directory never share a temporary file and a
+// failed save leaves nothing behind.
 func (fs *FileSaver) Save(ctx context.Context, data io.Reader, destination string) error {
 
 	dst, err := url.Parse(destination)
@@ -49,22 +61,51 @@ func (fs *FileSaver) Save(ctx context.Context, data io.Reader, destination strin
 		return fmt.Errorf("failed to parse destination URI: %w", err)
 	}
 
+	switch fs.Compress {
+	case "":
+	case "gzip":
+		if !strings.HasSuffix(dst.Path, ".gz") {
+			dst.Path += ".gz"
+		}
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedCompression, fs.Compress)
+	}
+
 	// Ensure the destination directory exists.
 	if err := os.MkdirAll(filepath.Dir(dst.Path), 0755); err != nil {
 		return fmt.Errorf("failed to create destination directory: %w", err)
 	}
 
-	// Create the destination file.
-	f, err := os.Create(dst.Path)
+	// Create the part file, which fails for a directory destination before any data is read.
+	f, err := CreatePart(dst.Path)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
+	defer f.Discard()
+
+	var w io.Writer = f
+	var gz *gzip.Writer
+	if fs.Compress == "gzip" {
+		gz = gzip.NewWriter(f)
+		w = gz
+	}
 
 	// Write the data to the file.
-	_, err = io.Copy(f, data)
+	_, err = io.Copy(w, data)
 	if err != nil {
 		return fmt.Errorf("failed to write data to file: %w", err)
 	}
+
+	// Flush the compressed data to the file.
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return fmt.Errorf("failed to write data to file: %w", err)
+		}
+	}
+
+	// Move the complete file into place.
+	if err := f.Commit(); err != nil {
+		return fmt.Errorf("failed to write data to file: %w", err)
+	}
 	return nil
 }
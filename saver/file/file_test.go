@@ -18,9 +18,14 @@ package file
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -146,3 +151,184 @@ func TestFileSaver_CopyError(t *testing.T) {
 		os.RemoveAll(destination)
 	})
 }
+
+// TestFileSaver_Compress tests saving data compressed with gzip.
+func TestFileSaver_Compress(t *testing.T) {
+	dir := t.TempDir()
+	testData := []byte("test data")
+
+	fs := &FileSaver{Compress: "gzip"}
+	for _, name := range []string{"file.txt", "file.txt.gz"} {
+		if err := fs.Save(context.Background(), bytes.NewReader(testData), filepath.Join(dir, name)); err != nil {
+			t.Fatalf("failed to save file: %v", err)
+		}
+
+		f, err := os.Open(filepath.Join(dir, "file.txt.gz"))
+		if err != nil {
+			t.Fatalf("failed to open saved file: %v", err)
+		}
+		defer f.Close()
+
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatalf("failed to read saved file as gzip: %v", err)
+		}
+		savedData, err := io.ReadAll(gz)
+		if err != nil {
+			t.Fatalf("failed to decompress saved file: %v", err)
+		}
+		if !bytes.Equal(savedData, testData) {
+			t.Errorf("unexpected saved data: got %s, want %s", savedData, testData)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "file.txt")); !os.IsNotExist(err) {
+		t.Errorf("expected no uncompressed file to be saved, but got: %v", err)
+	}
+
+	err := (&FileSaver{Compress: "bzip2"}).Save(context.Background(), bytes.NewReader(testData), filepath.Join(dir, "file.txt"))
+	if !errors.Is(err, ErrUnsupportedCompression) {
+		t.Errorf("expected ErrUnsupportedCompression, but got: %v", err)
+	}
+}
+
+// TestFileSaver_ConcurrentTempFiles tests that concurrent saves into the same directory write to distinct temporary
+// files, which are renamed to their destinations once complete.
+func TestFileSaver_ConcurrentTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	names := []string{"file.txt", "file.txt.part"}
+
+	fs := &FileSaver{}
+	writers := make([]*io.PipeWriter, len(names))
+	errs := make(chan error, len(names))
+	for i, name := range names {
+		r, w := io.Pipe()
+		writers[i] = w
+		go func() {
+			errs <- fs.Save(context.Background(), r, filepath.Join(dir, name))
+		}()
+		if _, err := w.Write([]byte(name)); err != nil {
+			t.Fatalf("failed to write data: %v", err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read directory: %v", err)
+	}
+	if len(entries) != len(names) {
+		t.Fatalf("expected %d temporary files, but got %d", len(names), len(entries))
+	}
+	for _, e := range entries {
+		if !strings.HasPrefix(e.Name(), ".file.txt.") || !strings.HasSuffix(e.Name(), ".part") {
+			t.Errorf("unexpected temporary file name: %s", e.Name())
+		}
+	}
+
+	for _, w := range writers {
+		w.Close()
+	}
+	for range names {
+		if err := <-errs; err != nil {
+			t.Fatalf("failed to save file: %v", err)
+		}
+	}
+
+	entries, err = os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read directory: %v", err)
+	}
+	if len(entries) != len(names) {
+		t.Errorf("expected only the saved files to remain, but got %d entries", len(entries))
+	}
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("failed to read saved file: %v", err)
+		}
+		if string(data) != name {
+			t.Errorf("unexpected saved data: got %s, want %s", data, name)
+		}
+	}
+}
+
+// TestFileSaver_DirectoryDestination tests that saving to an existing directory fails without reading the data.
+func TestFileSaver_DirectoryDestination(t *testing.T) {
+	destination := filepath.Join(t.TempDir(), "out.d")
+	if err := os.Mkdir(destination, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	data := strings.NewReader("test data")
+	err := (&FileSaver{}).Save(context.Background(), data, destination)
+	if err == nil || !strings.Contains(err.Error(), "is a directory") {
+		t.Errorf("expected an is a directory error, but got: %v", err)
+	}
+	if data.Len() != len("test data") {
+		t.Errorf("expected no data to be read, but %d bytes were", len("test data")-data.Len())
+	}
+}
+
+// TestFileSaver_Mode tests that a saved file has the mode os.Create would give it, or the mode of the file it replaces.
+func TestFileSaver_Mode(t *testing.T) {
+	dir := t.TempDir()
+	reference, err := os.Create(filepath.Join(dir, "reference"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reference.Close()
+	expected, err := os.Stat(reference.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := &FileSaver{}
+	if err := fs.Save(context.Background(), strings.NewReader("new"), filepath.Join(dir, "new.txt")); err != nil {
+		t.Fatalf("failed to save file: %v", err)
+	}
+	if info, err := os.Stat(filepath.Join(dir, "new.txt")); err != nil || info.Mode() != expected.Mode() {
+		t.Errorf("unexpected mode of a new file: got %v (%v), want %v", info.Mode(), err, expected.Mode())
+	}
+
+	existing := filepath.Join(dir, "existing.txt")
+	if err := os.WriteFile(existing, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(existing, 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Save(context.Background(), strings.NewReader("new"), existing); err != nil {
+		t.Fatalf("failed to save file: %v", err)
+	}
+	if info, err := os.Stat(existing); err != nil || info.Mode().Perm() != 0640 {
+		t.Errorf("unexpected mode of a replaced file: got %v (%v), want %v", info.Mode(), err, os.FileMode(0640))
+	}
+}
+
+// TestFileSaver_Symlink tests that saving to a symbolic link replaces the file it links to and keeps the link.
+func TestFileSaver_Symlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target.txt")
+	if err := os.WriteFile(target, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	links := map[string]string{"link.txt": "target.txt", "dangling.txt": "missing.txt"}
+	for link, to := range links {
+		if err := os.Symlink(to, filepath.Join(dir, link)); err != nil {
+			t.Skipf("symbolic links are not supported: %v", err)
+		}
+	}
+
+	fs := &FileSaver{}
+	for link, to := range links {
+		if err := fs.Save(context.Background(), strings.NewReader(link), filepath.Join(dir, link)); err != nil {
+			t.Fatalf("failed to save file: %v", err)
+		}
+		if info, err := os.Lstat(filepath.Join(dir, link)); err != nil || info.Mode()&os.ModeSymlink == 0 {
+			t.Errorf("expected %s to remain a symbolic link, got %v (%v)", link, info.Mode(), err)
+		}
+		if data, err := os.ReadFile(filepath.Join(dir, to)); err != nil || string(data) != link {
+			t.Errorf("unexpected content of %s: got %q (%v), want %q", to, data, err, link)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package file
+
+import (
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+)
+
+// maxSymlinks is the most symbolic links followed when resolving the destination of a PartFile
+const maxSymlinks = 255
+
+// PartFile is a uniquely named ".<filename>.<random>.part" file that data is written to before it replaces its
+// destination, so concurrent writes into the same directory never share a file and a failed write leaves the
+// destination as it was.
+type PartFile struct {
+	*os.File
+	destination string
+	committed   bool
+}
+
+// CreatePart creates the PartFile for the destination path. If the destination is a symbolic link, the part file
+// replaces the file it links to, and the link is kept. The part file has the mode of an existing destination, or
+// otherwise the mode os.Create would give it. Creating a PartFile for a directory fails.
+func CreatePart(destination string) (*PartFile, error) {
+	target := resolveSymlinks(destination)
+
+	var mode os.FileMode
+	if info, err := os.Stat(target); err == nil {
+		if info.IsDir() {
+			return nil, &os.PathError{Op: "open", Path: destination, Err: syscall.EISDIR}
+		}
+		mode = info.Mode().Perm()
+	}
+
+	dir, name := filepath.Split(target)
+	for {
+		path := filepath.Join(dir, "."+name+"."+strconv.FormatUint(uint64(rand.Uint32()), 10)+".part")
+		// Like os.Create, the umask applies to the mode the file is created with
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			// Report the error against the destination the part file stands in for
+			var pathErr *os.PathError
+			if errors.As(err, &pathErr) {
+				pathErr.Path = destination
+			}
+			return nil, err
+		}
+
+		if mode != 0 {
+			if err := f.Chmod(mode); err != nil {
+				f.Close()
+				_ = os.Remove(path)
+				return nil, err
+			}
+		}
+		return &PartFile{File: f, destination: target}, nil
+	}
+}
+
+// Commit closes the part file and renames it to its destination.
+func (p *PartFile) Commit() error {
+	if err := p.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(p.Name(), p.destination); err != nil {
+		return err
+	}
+	p.committed = true
+	return nil
+}
+
+// Discard closes and removes the part file, unless it has been committed.
+func (p *PartFile) Discard() {
+	if p.committed {
+		return
+	}
+	p.Close()
+	_ = os.Remove(p.Name())
+}
+
+// resolveSymlinks returns the path that path refers to once any symbolic links are followed, including a link
+// to a file that does not exist yet.
+func resolveSymlinks(path string) string {
+	for i := 0; i < maxSymlinks; i++ {
+		info, err := os.Lstat(path)
+		if err != nil || info.Mode()&os.ModeSymlink == 0 {
+			return path
+		}
+		target, err := os.Readlink(path)
+		if err != nil {
+			return path
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
+		}
+		path = target
+	}
+	return path
+}
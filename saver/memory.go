@@ -0,0 +1,144 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package saver
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing/fstest"
+	"time"
+)
+
+// FS is a filesystem that gatherers write their destination to in place of the local filesystem, such as a
+// MemoryFS. Like Save, its methods name files by destination: a local path or a file URI.
+type FS interface {
+	Saver
+	// MkdirAll creates the directory at the destination, along with any parents.
+	MkdirAll(destination string, perm fs.FileMode) error
+	// Open opens the file or directory at the destination for reading.
+	Open(destination string) (fs.File, error)
+	// Remove removes the file or empty directory at the destination.
+	Remove(destination string) error
+}
+
+// Sub returns the files of fsys within the directory at the destination, named relative to it.
+func Sub(fsys FS, destination string) fs.FS {
+	return subFS{fsys: fsys, dir: destination}
+}
+
+// subFS is the fs.FS returned by Sub
+type subFS struct {
+	fsys FS
+	dir  string
+}
+
+func (s subFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	return s.fsys.Open(strings.TrimSuffix(s.dir, "/") + "/" + name)
+}
+
+// MemoryFS is an FS that holds the files saved to it in memory, for tests and sandboxed environments that should
+// not write to disk. Destinations are named by their path, so "/data/a.txt" and "file:///data/a.txt" are the same
+// file, and the files are also readable as an fs.FS by the path without its leading separator, e.g. "data/a.txt".
+type MemoryFS struct {
+	mu    sync.Mutex
+	files fstest.MapFS
+}
+
+// NewMemoryFS returns an empty MemoryFS.
+func NewMemoryFS() *MemoryFS {
+	return &MemoryFS{files: fstest.MapFS{}}
+}
+
+// memoryName returns the name of the destination within a MemoryFS
+func memoryName(destination string) string {
+	destination = strings.TrimPrefix(destination, "file::")
+	if u, err := url.Parse(destination); err == nil && u.Scheme == "file" {
+		destination = u.Path
+	}
+	name := strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(destination)), "/")
+	if name == "" {
+		return "."
+	}
+	return name
+}
+
+// Save implements the Saver interface, holding the data as the file at the destination.
+func (m *MemoryFS) Save(ctx context.Context, data io.Reader, destination string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	content, err := io.ReadAll(data)
+	if err != nil {
+		return fmt.Errorf("failed to read data: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name := memoryName(destination)
+	if f, ok := m.files[name]; ok && f.Mode.IsDir() {
+		return &fs.PathError{Op: "open", Path: destination, Err: fs.ErrExist}
+	}
+	m.files[name] = &fstest.MapFile{Data: content, Mode: 0644, ModTime: time.Now()}
+	return nil
+}
+
+// MkdirAll implements the FS interface. Parent directories of the files saved are implied, so this is only
+// needed to hold empty directories.
+func (m *MemoryFS) MkdirAll(destination string, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name := memoryName(destination)
+	if f, ok := m.files[name]; ok {
+		if !f.Mode.IsDir() {
+			return &fs.PathError{Op: "mkdir", Path: destination, Err: fs.ErrExist}
+		}
+		return nil
+	}
+	if name != "." {
+		m.files[name] = &fstest.MapFile{Mode: fs.ModeDir | perm.Perm(), ModTime: time.Now()}
+	}
+	return nil
+}
+
+// Open implements the FS and fs.FS interfaces.
+func (m *MemoryFS) Open(destination string) (fs.File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.files.Open(memoryName(destination))
+}
+
+// Remove implements the FS interface.
+func (m *MemoryFS) Remove(destination string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name := memoryName(destination)
+	if _, ok := m.files[name]; !ok {
+		return &fs.PathError{Op: "remove", Path: destination, Err: fs.ErrNotExist}
+	}
+	delete(m.files, name)
+	return nil
+}
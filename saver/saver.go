@@ -24,6 +24,9 @@
 // Currently, the only supported protocol is "file", which creates a FileSaver instance for saving data to a file.
 // If an unsupported protocol is provided, NewSaver returns an error.
 //
+// The FS interface extends Saver with reading back what was saved, so that gatherers can write to a filesystem other
+// than the local one. MemoryFS is an FS that holds the saved files in memory.
+//
 // Example usage:
 //
 //	s, err := saver.NewSaver("file")
@@ -38,12 +41,16 @@ package saver
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 
 	"github.com/enterprise-contract/go-gather/saver/file"
 )
 
+// ErrUnsupportedProtocol is returned when no Saver exists for the destination protocol.
+var ErrUnsupportedProtocol = errors.New("unsupported protocol")
+
 // Saver is an interface for saving data to a destination.
 type Saver interface {
 	Save(ctx context.Context, data io.Reader, destination string) error
@@ -55,6 +62,6 @@ func NewSaver(protocol string) (Saver, error) {
 	case "file", "FileURI":
 		return &file.FileSaver{}, nil
 	default:
-		return nil, fmt.Errorf("unsupported protocol: %s", protocol)
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedProtocol, protocol)
 	}
 }
@@ -17,7 +17,11 @@
 package saver
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
+	"strings"
 	"testing"
 
 	"github.com/enterprise-contract/go-gather/saver/file"
@@ -44,4 +48,56 @@ func TestNewSaver(t *testing.T) {
 	} else if err.Error() != expectedErr.Error() {
 		t.Errorf("unexpected error: got %v, want %v", err, expectedErr)
 	}
+	if !errors.Is(err, ErrUnsupportedProtocol) {
+		t.Errorf("expected error to wrap ErrUnsupportedProtocol, got %v", err)
+	}
+}
+
+func TestMemoryFS(t *testing.T) {
+	m := NewMemoryFS()
+	ctx := context.Background()
+
+	if err := m.Save(ctx, strings.NewReader("a"), "/data/a.txt"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := m.Save(ctx, strings.NewReader("b"), "file:///data/sub/b.txt"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := m.MkdirAll("/data/empty", 0755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Destinations and fs.FS names refer to the same files
+	for name, expected := range map[string]string{"data/a.txt": "a", "/data/sub/b.txt": "b", "file::/data/a.txt": "a"} {
+		content, err := fs.ReadFile(m, name)
+		if err != nil || string(content) != expected {
+			t.Errorf("unexpected content of %s: got %q (%v), want %q", name, content, err, expected)
+		}
+	}
+
+	entries, err := fs.ReadDir(Sub(m, "/data"), ".")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if fmt.Sprint(names) != "[a.txt empty sub]" {
+		t.Errorf("unexpected directory entries: %v", names)
+	}
+
+	if err := m.Save(ctx, strings.NewReader("c"), "/data/empty"); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("expected saving over a directory to fail with fs.ErrExist, got %v", err)
+	}
+
+	if err := m.Remove("/data/a.txt"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := m.Open("/data/a.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected a removed file not to exist, got %v", err)
+	}
+	if err := m.Remove("/data/a.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected removing a missing file to fail with fs.ErrNotExist, got %v", err)
+	}
 }